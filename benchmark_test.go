@@ -0,0 +1,95 @@
+package butter
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+/*benchmarkPrograms are loop and call heavy scripts, the workloads variable lookups dominate */
+var benchmarkPrograms = []struct {
+	name   string
+	source string
+}{
+	{"loop", `
+int total := 0
+for int i := 0; i < 20000; i := i + 1 {
+  int doubled := i * 2
+  total := total + doubled
+}
+`},
+	{"nested blocks", `
+fn run() int {
+  int total := 0
+  int i := 0
+  while i < 20000 {
+    if i % 2 == 0 {
+      int step := 2
+      total := total + step
+    } else {
+      total := total + 1
+    }
+    i := i + 1
+  }
+  return total
+}
+run()
+`},
+	{"fib", `
+fn fib(int n) int {
+  if n < 2 {
+    return n
+  }
+  return fib(n - 1) + fib(n - 2)
+}
+fib(18)
+`},
+	{"closures", `
+fn counter() {
+  int count := 0
+  fn inc() int {
+    count := count + 1
+    return count
+  }
+  return inc
+}
+var next := counter()
+for int i := 0; i < 10000; i := i + 1 {
+  next()
+}
+`},
+	{"lists", `
+list<int> xs := []
+for int i := 0; i < 5000; i := i + 1 {
+  append(xs, i)
+}
+int total := 0
+for x in xs {
+  total := total + x
+}
+`},
+}
+
+func BenchmarkInterpreter(b *testing.B) {
+	benchmarkBackend(b, false)
+}
+
+func BenchmarkBytecode(b *testing.B) {
+	benchmarkBackend(b, true)
+}
+
+/*benchmarkBackend runs every benchmark program on a fresh runtime using the chosen backend */
+func benchmarkBackend(b *testing.B, bytecode bool) {
+	for _, program := range benchmarkPrograms {
+		b.Run(program.name, func(b *testing.B) {
+			source := NewSource("bench", program.source)
+			for n := 0; n < b.N; n++ {
+				runtime := New()
+				runtime.UseBytecode(bytecode)
+				runtime.SetOutput(ioutil.Discard)
+				if _, err := runtime.EvalSource(source); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package butter
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+/*Builtin is a function implemented in Go which is available to every program */
+type Builtin struct {
+	name  string
+	arity int
+	fn    func(interpreter *Interpreter, args []Object) Object
+}
+
+/*Type returns a string representation of the builtin object's type */
+func (b *Builtin) Type() string {
+	return string(BUILTINOBJ)
+}
+
+/*Arity returns the number of arguments the builtin expects */
+func (b *Builtin) Arity() int {
+	return b.arity
+}
+
+/*Call runs the builtin with the passed arguments */
+func (b *Builtin) Call(interpreter *Interpreter, args []Object) Object {
+	return b.fn(interpreter, args)
+}
+
+/*builtins are defined in the global environment of every interpreter */
+var builtins = []*Builtin{
+	{"len", 1, builtinLen},
+	{"append", 2, builtinAppend},
+	{"delete", 2, builtinDelete},
+	{"keys", 1, builtinKeys},
+	{"values", 1, builtinValues},
+}
+
+/*DefineBuiltins defines every builtin function in the passed environment */
+func DefineBuiltins(env *Env) {
+	for _, builtin := range builtins {
+		env.define(builtin.name, builtin)
+	}
+}
+
+/*builtinLen returns the number of elements in a list, entries in a map or characters in a string */
+func builtinLen(interpreter *Interpreter, args []Object) Object {
+	switch arg := args[0].(type) {
+	case *List:
+		return Integer{len(arg.Elements)}
+	case *Map:
+		return Integer{arg.Len()}
+	case String:
+		return Integer{utf8.RuneCountInString(arg.Value)}
+	}
+	typeError(fmt.Sprintf("len() is not supported for '%s'", args[0].Type()))
+	return NIL
+}
+
+/*builtinAppend adds a value to the end of a list, checking it against the list's element type */
+func builtinAppend(interpreter *Interpreter, args []Object) Object {
+	list, ok := args[0].(*List)
+	if !ok {
+		typeError(fmt.Sprintf("append() expects a list, received '%s'", args[0].Type()))
+	}
+	if list.elemType != nil {
+		CheckVarType(*list.elemType, args[1])
+	}
+	list.Elements = append(list.Elements, args[1])
+	return list
+}
+
+/*builtinDelete removes a key from a map, returning whether the key was present */
+func builtinDelete(interpreter *Interpreter, args []Object) Object {
+	m := expectMap("delete", args[0])
+	return Boolean{m.Delete(args[1])}
+}
+
+/*builtinKeys returns a list of a map's keys in insertion order */
+func builtinKeys(interpreter *Interpreter, args []Object) Object {
+	m := expectMap("keys", args[0])
+	return &List{m.Keys(), m.keyType}
+}
+
+/*builtinValues returns a list of a map's values in the insertion order of their keys */
+func builtinValues(interpreter *Interpreter, args []Object) Object {
+	m := expectMap("values", args[0])
+	values := make([]Object, 0, m.Len())
+	for _, key := range m.keys {
+		values = append(values, m.values[key])
+	}
+	return &List{values, m.valueType}
+}
+
+func expectMap(name string, arg Object) *Map {
+	m, ok := arg.(*Map)
+	if !ok {
+		typeError(fmt.Sprintf("%s() expects a map, received '%s'", name, arg.Type()))
+	}
+	return m
+}
@@ -0,0 +1,224 @@
+package butter
+
+import (
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+/*Global is a variable defined at the top level of a runtime, along with its declared or inferred type */
+type Global struct {
+	Name  string
+	Type  string
+	Value Object
+}
+
+/*VERSION is the version of the Butter language implemented by this package */
+var VERSION string = "0.1"
+
+/*Runtime is an embeddable Butter interpreter. Globals defined by one call to Eval are visible to the next */
+type Runtime struct {
+	interpreter *Interpreter
+	machine     *Machine
+	repl        bool
+}
+
+/*New returns a Runtime with an empty global environment which prints to stdout */
+func New() *Runtime {
+	return &Runtime{interpreter: NewInterpreter()}
+}
+
+/*UseBytecode switches between running programs on the tree-walking interpreter and compiling them to
+  bytecode for the stack machine. Both share the same globals and output */
+func (r *Runtime) UseBytecode(enabled bool) {
+	r.machine = nil
+	if enabled {
+		r.machine = NewMachine(r.interpreter)
+	}
+}
+
+/*UseREPL makes the runtime print the value of every top level expression statement it runs, the way the REPL
+  shows the result of each expression entered */
+func (r *Runtime) UseREPL(enabled bool) {
+	r.repl = enabled
+}
+
+/*SetOutput changes where print statements write to */
+func (r *Runtime) SetOutput(w io.Writer) {
+	r.interpreter.out = w
+}
+
+/*SetArgs defines the global args, a list<string> holding the arguments passed to the program, replacing any
+  arguments set before */
+func (r *Runtime) SetArgs(args []string) {
+	elemType := keywordType(STRINGTYPE, "string")
+	listType := keywordType(LISTTYPE, "list")
+	listType.params = []TypeSpec{elemType}
+	elements := make([]Object, 0, len(args))
+	for _, arg := range args {
+		elements = append(elements, String{Value: arg})
+	}
+	delete(r.interpreter.globals.values, "args")
+	r.interpreter.globals.declare("args", listType, &List{elements, &elemType})
+}
+
+/*Eval tokenizes, parses and runs the source. It returns the value of the final statement if it is an
+  expression, otherwise NIL. Failures are returned as ParseErrors, TypeCheckErrors or a *RuntimeError */
+func (r *Runtime) Eval(source string) (Object, error) {
+	return r.EvalSource(NewSource("<eval>", source))
+}
+
+/*EvalFile reads and runs the file at path, naming it in any error positions */
+func (r *Runtime) EvalFile(path string) (Object, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return NIL, err
+	}
+	return r.EvalSource(NewSource(path, string(text)))
+}
+
+/*EvalSource runs a named piece of source the same way as Eval. The source is resolved and type checked before
+  it runs, and nothing is run if any errors are found */
+func (r *Runtime) EvalSource(source *Source) (result Object, err error) {
+	stmts, err := Parse(source)
+	if err != nil {
+		return NIL, err
+	}
+	if err := Resolve(stmts, r.interpreter.globals); err != nil {
+		return NIL, err
+	}
+	if err := NewChecker(r.interpreter.env).Check(stmts); err != nil {
+		return NIL, err
+	}
+	if r.machine != nil {
+		function, err := Compile(stmts, r.repl)
+		if err != nil {
+			return NIL, err
+		}
+		return r.machine.Run(function)
+	}
+	defer r.recoverError(&err)
+	return r.interpreter.Interpret(stmts, r.repl), nil
+}
+
+/*TypeOf parses a single expression and returns its static type, checked against the runtime's globals. The
+  expression is not run, so it has no side effects */
+func (r *Runtime) TypeOf(source string) (string, error) {
+	src := NewSource("<type>", source)
+	stmts, err := Parse(src)
+	if err != nil {
+		return "", err
+	}
+	var exprStmt ExprStmt
+	if len(stmts) == 1 {
+		exprStmt, _ = stmts[0].(ExprStmt)
+	}
+	if exprStmt.expr == nil {
+		return "", ParseErrors{&ParseError{Position{src, 1, 1, 0, 0}, "Expect a single expression"}}
+	}
+	if err := Resolve(stmts, r.interpreter.globals); err != nil {
+		return "", err
+	}
+	return NewChecker(r.interpreter.globals).TypeOf(exprStmt.expr)
+}
+
+/*Globals returns the variables, functions and structs defined so far, sorted by name. Builtins are left out */
+func (r *Runtime) Globals() []Global {
+	checker := NewChecker(r.interpreter.globals)
+	var globals []Global
+	for name, value := range r.interpreter.globals.values {
+		if _, builtin := value.(*Builtin); builtin {
+			continue
+		}
+		globals = append(globals, Global{name, checker.scope.types[name].String(), value})
+	}
+	sort.Slice(globals, func(a, b int) bool { return globals[a].Name < globals[b].Name })
+	return globals
+}
+
+/*Names returns the names which can be used at the top level, every reserved word and every global defined
+  so far including the builtins, sorted. The REPL completes identifiers from them */
+func (r *Runtime) Names() []string {
+	names := ReservedWords()
+	for name := range r.interpreter.globals.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*Reset discards every global defined so far, leaving the runtime as it was when created apart from its output
+  and backend */
+func (r *Runtime) Reset() {
+	out := r.interpreter.out
+	r.interpreter = NewInterpreter()
+	r.interpreter.out = out
+	r.UseBytecode(r.machine != nil)
+}
+
+/*CheckFile reads the file at path and reports its syntax and type errors without running it */
+func (r *Runtime) CheckFile(path string) error {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return r.CheckSource(NewSource(path, string(text)))
+}
+
+/*CheckSource parses and type checks a named piece of source against the runtime's globals without running it.
+  Syntax and scope errors are returned as ParseErrors and type errors as TypeCheckErrors */
+func (r *Runtime) CheckSource(source *Source) error {
+	stmts, err := Parse(source)
+	if err != nil {
+		return err
+	}
+	if err := Resolve(stmts, r.interpreter.globals); err != nil {
+		return err
+	}
+	return NewChecker(r.interpreter.env).Check(stmts)
+}
+
+/*Parse tokenizes and parses the source into a list of statements without running them. If there are any
+  syntax errors, all of them are returned as ParseErrors */
+func Parse(source *Source) ([]Stmt, error) {
+	tokenizer := NewTokenizer(source)
+	tokens := tokenizer.Tokenize()
+	parser := NewParser(tokens)
+	stmts := parser.Parse()
+	errs := append(ParseErrors(tokenizer.Errors()), parser.Errors()...)
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(a, b int) bool { return errs[a].Pos.Offset < errs[b].Pos.Offset })
+		return nil, errs
+	}
+	return stmts, nil
+}
+
+/*Incomplete returns true if the source only fails to parse because it stops too early, inside a block, a
+  multi-line string or comment, or after an operator. The REPL keeps reading lines until its input is complete */
+func Incomplete(source string) bool {
+	_, err := Parse(NewSource("<repl>", source))
+	errs, ok := err.(ParseErrors)
+	if !ok {
+		return false
+	}
+	for _, err := range errs {
+		if err.Pos.Offset+err.Pos.Length < len(source) {
+			return false
+		}
+	}
+	return true
+}
+
+/*recoverError turns a RuntimeError raised while interpreting into a returned error, recording the position
+  the interpreter was at and the functions that were running when it was raised */
+func (r *Runtime) recoverError(err *error) {
+	if rec := recover(); rec != nil {
+		runtimeErr, ok := rec.(*RuntimeError)
+		if !ok {
+			panic(rec)
+		}
+		r.interpreter.CompleteError(runtimeErr)
+		r.interpreter.frames = nil
+		*err = runtimeErr
+	}
+}
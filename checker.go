@@ -0,0 +1,1092 @@
+package butter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*typeKind is the broad category of a type inferred by the Checker */
+type typeKind int
+
+const (
+	kindAny typeKind = iota
+	kindNil
+	kindInt
+	kindFloat
+	kindBool
+	kindString
+	kindList
+	kindMap
+	kindFunction
+	kindBuiltin
+	kindStruct
+	kindInstance
+	kindError
+)
+
+/*checkType is the static type of an expression. Values whose type can't be known before running, such as
+  the elements of an empty list literal, are of kind any and are never reported */
+type checkType struct {
+	kind typeKind
+	//name is the struct's name for structs and instances, and the builtin's name for builtins
+	name string
+	//params holds the element type of lists, the key and value types of maps and the parameters of functions
+	params []*checkType
+	//result is the return type of a function, nil if it was not declared
+	result *checkType
+}
+
+var (
+	anyType    = &checkType{kind: kindAny}
+	nilType    = &checkType{kind: kindNil}
+	intType    = &checkType{kind: kindInt}
+	floatType  = &checkType{kind: kindFloat}
+	boolType   = &checkType{kind: kindBool}
+	stringType = &checkType{kind: kindString}
+	errorType  = &checkType{kind: kindError}
+)
+
+func listOf(elem *checkType) *checkType {
+	return &checkType{kind: kindList, params: []*checkType{elem}}
+}
+
+func mapOf(key *checkType, value *checkType) *checkType {
+	return &checkType{kind: kindMap, params: []*checkType{key, value}}
+}
+
+/*String returns the type as it would be written in source */
+func (t *checkType) String() string {
+	switch t.kind {
+	case kindNil:
+		return "nil"
+	case kindInt:
+		return "int"
+	case kindFloat:
+		return "float"
+	case kindBool:
+		return "bool"
+	case kindString:
+		return "string"
+	case kindList:
+		return "list<" + t.params[0].String() + ">"
+	case kindMap:
+		return "map<" + t.params[0].String() + ", " + t.params[1].String() + ">"
+	case kindFunction:
+		params := make([]string, len(t.params))
+		for idx, param := range t.params {
+			params[idx] = param.String()
+		}
+		result := ""
+		if t.result != nil {
+			result = " " + t.result.String()
+		}
+		return "fn(" + strings.Join(params, ", ") + ")" + result
+	case kindBuiltin:
+		return "builtin " + t.name
+	case kindStruct:
+		return "struct " + t.name
+	case kindInstance:
+		return t.name
+	case kindError:
+		return "Error"
+	default:
+		return "any"
+	}
+}
+
+/*isNumber returns true for ints and floats */
+func (t *checkType) isNumber() bool {
+	return t.kind == kindInt || t.kind == kindFloat
+}
+
+/*assignable returns true if a value of type value can be stored where target is expected. Types must match
+  exactly, although any matches everything */
+func assignable(target *checkType, value *checkType) bool {
+	if target.kind == kindAny || value.kind == kindAny {
+		return true
+	}
+	if target.kind != value.kind || target.name != value.name {
+		return false
+	}
+	//functions are only compared by kind when they are assigned, like at runtime
+	if target.kind == kindFunction {
+		return true
+	}
+	for idx := range target.params {
+		if !assignable(target.params[idx], value.params[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+/*unify returns the type shared by every entry of a literal, reporting the first entry whose type differs from
+  the ones before it. Mixed or empty literals are given type any */
+func (c *Checker) unify(entries []Expr, types []*checkType, what string) *checkType {
+	if len(types) == 0 {
+		return anyType
+	}
+	for idx, t := range types[1:] {
+		if !assignable(types[0], t) || !assignable(t, types[0]) {
+			c.errorAt(entries[idx+1].Position(), "cannot mix %s of type %s and %s in one literal", what, types[0], t)
+			return anyType
+		}
+	}
+	return types[0]
+}
+
+/*TypeCheckError is returned when the checker finds an operation which would fail with a type error */
+type TypeCheckError struct {
+	Pos     Position
+	Message string
+}
+
+func (e *TypeCheckError) Error() string {
+	return fmt.Sprintf("TYPE_ERROR [%s]: %s", e.Pos, e.Message)
+}
+
+/*TypeCheckErrors is returned when one or more type errors are found, holding every error in source order */
+type TypeCheckErrors []*TypeCheckError
+
+func (e TypeCheckErrors) Error() string {
+	messages := make([]string, len(e))
+	for idx, err := range e {
+		messages[idx] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+/*checkScope maps the variables visible in a block to their types */
+type checkScope struct {
+	parent *checkScope
+	types  map[string]*checkType
+}
+
+func (s *checkScope) lookup(name string) (*checkType, bool) {
+	for scope := s; scope != nil; scope = scope.parent {
+		if t, ok := scope.types[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+/*structInfo is what the checker knows about a declared struct */
+type structInfo struct {
+	declaration StructDeclaration
+	fields      map[string]*checkType
+	methods     map[string]*checkType
+}
+
+/*Checker walks a parsed program before it runs, inferring the type of every expression and reporting the
+  operations which are certain to fail with a type error, even in code which would never be reached */
+type Checker struct {
+	scope   *checkScope
+	structs map[string]*structInfo
+	//function is the declaration of the function whose body is being checked, nil at the top level
+	function *FuncDeclaration
+	result   *checkType
+	//pending are the functions whose bodies are left until the rest of the program has been checked
+	pending []pendingFunction
+	errors  TypeCheckErrors
+}
+
+/*pendingFunction is a function whose body hasn't been checked yet, along with the scope it was declared in */
+type pendingFunction struct {
+	declaration FuncDeclaration
+	scope       *checkScope
+}
+
+/*NewChecker returns a checker whose global scope holds the variables already defined in env, so code can
+  be checked against an interpreter's existing state */
+func NewChecker(env *Env) *Checker {
+	c := &Checker{structs: make(map[string]*structInfo)}
+	c.scope = &checkScope{nil, make(map[string]*checkType)}
+	//structs are declared first so the types of other values can refer to them
+	var structs []*Struct
+	for scope := env; scope != nil; scope = scope.parent {
+		for _, value := range scope.values {
+			if structType, ok := value.(*Struct); ok {
+				c.declareStruct(structType.declaration)
+				structs = append(structs, structType)
+			}
+		}
+	}
+	for _, structType := range structs {
+		info := c.structs[structType.declaration.name.literal]
+		for _, field := range structType.declaration.fields {
+			info.fields[field.name.literal] = c.resolve(field.varType)
+		}
+		for name, method := range structType.methods {
+			info.methods[name] = c.functionType(method.Declaration())
+		}
+	}
+	for scope := env; scope != nil; scope = scope.parent {
+		for name, value := range scope.values {
+			if _, shadowed := c.scope.types[name]; shadowed {
+				continue
+			}
+			if spec, ok := scope.types[name]; ok {
+				c.scope.types[name] = c.resolve(spec)
+			} else {
+				c.scope.types[name] = c.typeOfValue(value)
+			}
+		}
+	}
+	return c
+}
+
+/*Check reports every type error in a program which is run with only the builtins defined */
+func Check(stmts []Stmt) error {
+	env := NewEnvironment(nil)
+	DefineBuiltins(env)
+	return NewChecker(env).Check(stmts)
+}
+
+/*Check checks a list of top level statements, returning all of the type errors found as TypeCheckErrors */
+func (c *Checker) Check(stmts []Stmt) error {
+	c.errors = nil
+	c.checkBlock(stmts)
+	c.checkPending()
+	if len(c.errors) > 0 {
+		errs := c.errors
+		sort.SliceStable(errs, func(a, b int) bool { return errs[a].Pos.Offset < errs[b].Pos.Offset })
+		return errs
+	}
+	return nil
+}
+
+/*TypeOf infers the type of a single expression without running it, returning any type errors within it as
+  TypeCheckErrors */
+func (c *Checker) TypeOf(e Expr) (string, error) {
+	c.errors = nil
+	t := c.checkExpr(e)
+	if len(c.errors) > 0 {
+		return "", c.errors
+	}
+	return t.String(), nil
+}
+
+func (c *Checker) errorAt(pos Position, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	//the types in a function's signature are resolved both when it is declared and when its body is checked
+	for _, err := range c.errors {
+		if err.Pos == pos && err.Message == message {
+			return
+		}
+	}
+	c.errors = append(c.errors, &TypeCheckError{pos, message})
+}
+
+/*typeOfValue returns the static type of a value which already exists */
+func (c *Checker) typeOfValue(value Object) *checkType {
+	switch v := value.(type) {
+	case Integer:
+		return intType
+	case Float:
+		return floatType
+	case Boolean:
+		return boolType
+	case String:
+		return stringType
+	case *ErrorValue:
+		return errorType
+	case *Builtin:
+		return &checkType{kind: kindBuiltin, name: v.name}
+	case Method:
+		return c.functionType(v.Declaration())
+	case *Struct:
+		return &checkType{kind: kindStruct, name: v.declaration.name.literal}
+	case *Instance:
+		return &checkType{kind: kindInstance, name: v.Type()}
+	case *List:
+		if v.elemType != nil {
+			return listOf(c.resolve(*v.elemType))
+		}
+		return listOf(anyType)
+	case *Map:
+		if v.keyType != nil {
+			return mapOf(c.resolve(*v.keyType), c.resolve(*v.valueType))
+		}
+		return mapOf(anyType, anyType)
+	}
+	return anyType
+}
+
+/*resolve converts a declared type to a checker type, reporting struct names which have not been declared */
+func (c *Checker) resolve(spec TypeSpec) *checkType {
+	switch spec.token.Type {
+	case INTTYPE:
+		return intType
+	case FLOATTYPE:
+		return floatType
+	case BOOLTYPE:
+		return boolType
+	case STRINGTYPE:
+		return stringType
+	case LISTTYPE:
+		return listOf(c.resolve(spec.params[0]))
+	case MAPTYPE:
+		return mapOf(c.resolve(spec.params[0]), c.resolve(spec.params[1]))
+	case IDENTIFIER:
+		if _, ok := c.structs[spec.token.literal]; !ok {
+			c.errorAt(spec.token.pos, "unknown type '%s'", spec.token.literal)
+			return anyType
+		}
+		return &checkType{kind: kindInstance, name: spec.token.literal}
+	}
+	return anyType
+}
+
+/*functionType returns the type of a declared function, any result if it declares no return type */
+func (c *Checker) functionType(fd FuncDeclaration) *checkType {
+	t := &checkType{kind: kindFunction}
+	for _, param := range fd.params {
+		t.params = append(t.params, c.resolve(param.varType))
+	}
+	if fd.returnType != nil {
+		t.result = c.resolve(*fd.returnType)
+	}
+	return t
+}
+
+/*declareStruct records a struct's name so its fields and other declarations can refer to it */
+func (c *Checker) declareStruct(sd StructDeclaration) {
+	if _, ok := c.structs[sd.name.literal]; ok {
+		return
+	}
+	c.structs[sd.name.literal] = &structInfo{sd, make(map[string]*checkType), make(map[string]*checkType)}
+}
+
+/*declareMethod attaches a method's type to the struct named by its receiver */
+func (c *Checker) declareMethod(fd FuncDeclaration) {
+	info, ok := c.structs[fd.receiver.varType.token.literal]
+	if !ok {
+		//the receiver's type is reported as unknown when the method's body is checked
+		return
+	}
+	name := fd.name.literal
+	if _, ok := info.fields[name]; ok {
+		c.errorAt(fd.name.pos, "'%s' already has a field named '%s'", info.declaration.name.literal, name)
+		return
+	}
+	if _, ok := info.methods[name]; ok {
+		c.errorAt(fd.name.pos, "'%s' already has a method named '%s'", info.declaration.name.literal, name)
+		return
+	}
+	info.methods[name] = c.functionType(fd)
+}
+
+/*hoist declares the structs and functions of a block before any of it is checked, so that function bodies
+  can refer to declarations that come after them */
+func (c *Checker) hoist(stmts []Stmt) {
+	for _, stmt := range stmts {
+		if sd, ok := stmt.(StructDeclaration); ok {
+			c.declareStruct(sd)
+			c.scope.types[sd.name.literal] = &checkType{kind: kindStruct, name: sd.name.literal}
+		}
+	}
+	for _, stmt := range stmts {
+		if sd, ok := stmt.(StructDeclaration); ok {
+			info := c.structs[sd.name.literal]
+			for _, field := range sd.fields {
+				info.fields[field.name.literal] = c.resolve(field.varType)
+			}
+		}
+	}
+	for _, stmt := range stmts {
+		if fd, ok := stmt.(FuncDeclaration); ok {
+			if fd.receiver != nil {
+				c.declareMethod(fd)
+			} else {
+				c.scope.types[fd.name.literal] = c.functionType(fd)
+			}
+		}
+	}
+}
+
+/*checkBlock checks a list of statements in the current scope */
+func (c *Checker) checkBlock(stmts []Stmt) {
+	c.hoist(stmts)
+	for _, stmt := range stmts {
+		c.checkStmt(stmt)
+	}
+}
+
+/*checkScoped checks a list of statements in a new scope, with the passed variables defined */
+func (c *Checker) checkScoped(stmts []Stmt, defined map[string]*checkType) {
+	if defined == nil {
+		defined = make(map[string]*checkType)
+	}
+	prev := c.scope
+	c.scope = &checkScope{prev, defined}
+	c.checkBlock(stmts)
+	c.scope = prev
+}
+
+/*checkBody checks the body of an if statement or loop, which may or may not be a block */
+func (c *Checker) checkBody(body Stmt, defined map[string]*checkType) {
+	if block, ok := body.(Block); ok {
+		c.checkScoped(block.stmts, defined)
+		return
+	}
+	c.checkScoped([]Stmt{body}, defined)
+}
+
+func (c *Checker) checkStmt(stmt Stmt) {
+	switch s := stmt.(type) {
+	case Print:
+		c.checkExpr(s.expr)
+	case ExprStmt:
+		c.checkExpr(s.expr)
+	case VarDeclaration:
+		if s.varType.Inferred() {
+			c.checkInferred(s)
+			return
+		}
+		varType := c.resolve(s.varType)
+		if s.initializer != nil {
+			value := c.checkAgainst(s.initializer, varType)
+			if !assignable(varType, value) {
+				c.errorAt(s.initializer.Position(), "cannot assign value of type %s to %s variable '%s'", value, varType, s.identifier.literal)
+			}
+		}
+		c.scope.types[s.identifier.literal] = varType
+	case If:
+		c.checkCondition(s.condition, "if conditional")
+		c.checkBody(s.ifTrue, nil)
+		if s.ifFalse != nil {
+			c.checkBody(s.ifFalse, nil)
+		}
+	case While:
+		c.checkCondition(s.condition, "while condition")
+		c.checkBody(s.body, nil)
+	case For:
+		prev := c.scope
+		c.scope = &checkScope{prev, make(map[string]*checkType)}
+		if s.initializer != nil {
+			c.checkStmt(s.initializer)
+		}
+		if s.condition != nil {
+			c.checkCondition(s.condition, "for condition")
+		}
+		if s.increment != nil {
+			c.checkExpr(s.increment)
+		}
+		c.checkBody(s.body, nil)
+		c.scope = prev
+	case ForIn:
+		c.checkForIn(s)
+	case Block:
+		c.checkScoped(s.stmts, nil)
+	case FuncDeclaration:
+		c.pending = append(c.pending, pendingFunction{s, c.scope})
+	case StructDeclaration:
+		//the fields were resolved when the struct was hoisted
+	case Return:
+		c.checkReturn(s)
+	case Try:
+		c.checkScoped(s.body, nil)
+		if s.catchName != nil {
+			c.checkScoped(s.catchBody, map[string]*checkType{s.catchName.literal: errorType})
+		}
+		if s.finally != nil {
+			c.checkScoped(s.finally, nil)
+		}
+	case Throw:
+		c.checkExpr(s.value)
+	}
+}
+
+/*checkInferred declares a var or let variable with the type of its initializer. Collection literals whose
+  entries don't share a type can't be inferred */
+func (c *Checker) checkInferred(vd VarDeclaration) {
+	reported := len(c.errors)
+	varType := c.checkExpr(vd.initializer)
+	switch vd.initializer.(type) {
+	case ListLiteral, MapLiteral:
+		if len(c.errors) > reported {
+			//a mixed literal has already been reported
+			break
+		}
+		if !inferable(vd.initializer) {
+			c.errorAt(vd.initializer.Position(), "cannot infer the type of '%s' from an empty or mixed collection, declare its type instead", vd.identifier.literal)
+		}
+	}
+	c.scope.types[vd.identifier.literal] = varType
+}
+
+/*inferable returns false if a collection literal, or any literal nested inside it, is empty. The runtime can't
+  tell the type of an empty literal's entries */
+func inferable(e Expr) bool {
+	switch l := e.(type) {
+	case ListLiteral:
+		if len(l.elements) == 0 {
+			return false
+		}
+		for _, element := range l.elements {
+			if !inferable(element) {
+				return false
+			}
+		}
+	case MapLiteral:
+		if len(l.keys) == 0 {
+			return false
+		}
+		for idx := range l.keys {
+			if !inferable(l.keys[idx]) || !inferable(l.values[idx]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+/*checkCondition reports conditions which are not booleans */
+func (c *Checker) checkCondition(condition Expr, context string) {
+	t := c.checkExpr(condition)
+	if !assignable(boolType, t) {
+		c.errorAt(condition.Position(), "cannot use value of type %s in %s", t, context)
+	}
+}
+
+/*checkForIn defines the loop variables from the element types of the list or map being iterated over */
+func (c *Checker) checkForIn(f ForIn) {
+	iterable := c.checkExpr(f.iterable)
+	first, second := anyType, anyType
+	switch iterable.kind {
+	case kindList:
+		first, second = intType, iterable.params[0]
+		if len(f.names) == 1 {
+			first = second
+		}
+	case kindMap:
+		first, second = iterable.params[0], iterable.params[1]
+	case kindAny:
+	default:
+		c.errorAt(f.iterable.Position(), "cannot iterate over value of type %s", iterable)
+	}
+	defined := map[string]*checkType{f.names[0].literal: first}
+	if len(f.names) == 2 {
+		defined[f.names[1].literal] = second
+	}
+	c.checkBody(f.body, defined)
+}
+
+/*checkPending checks the bodies of the functions declared so far. A function may use variables declared after
+  it, so its body is only checked once every scope it can see has been filled in */
+func (c *Checker) checkPending() {
+	prev := c.scope
+	for len(c.pending) > 0 {
+		next := c.pending[0]
+		c.pending = c.pending[1:]
+		c.scope = next.scope
+		c.checkFunction(next.declaration)
+	}
+	c.scope = prev
+}
+
+/*checkFunction checks a function body with its parameters (and receiver, for methods) defined */
+func (c *Checker) checkFunction(fd FuncDeclaration) {
+	defined := make(map[string]*checkType)
+	if fd.receiver != nil {
+		defined[fd.receiver.name.literal] = c.resolve(fd.receiver.varType)
+	}
+	for _, param := range fd.params {
+		defined[param.name.literal] = c.resolve(param.varType)
+	}
+	prevFunction, prevResult := c.function, c.result
+	c.function, c.result = &fd, nil
+	if fd.returnType != nil {
+		c.result = c.resolve(*fd.returnType)
+	}
+	c.checkScoped(fd.body, defined)
+	if c.result != nil && !returns(fd.body) {
+		c.errorAt(fd.name.pos, "'%s' must return a value of type %s, but can finish without returning", fd.name.literal, c.result)
+	}
+	c.function, c.result = prevFunction, prevResult
+}
+
+/*returns returns true if running the statements always ends in a return or a throw. Loops only count when they
+  can never finish, like a while true loop which doesn't break out */
+func returns(stmts []Stmt) bool {
+	for _, stmt := range stmts {
+		if stmtReturns(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func stmtReturns(stmt Stmt) bool {
+	switch s := stmt.(type) {
+	case Return, Throw:
+		return true
+	case Block:
+		return returns(s.stmts)
+	case If:
+		return s.ifFalse != nil && stmtReturns(s.ifTrue) && stmtReturns(s.ifFalse)
+	case Try:
+		if returns(s.finally) {
+			return true
+		}
+		return returns(s.body) && (s.catchName == nil || returns(s.catchBody))
+	case While:
+		return alwaysTrue(s.condition) && !breaksOut(s.body, s.label, true)
+	case For:
+		return (s.condition == nil || alwaysTrue(s.condition)) && !breaksOut(s.body, s.label, true)
+	}
+	return false
+}
+
+func alwaysTrue(condition Expr) bool {
+	switch e := condition.(type) {
+	case Grouping:
+		return alwaysTrue(e.expr)
+	case Literal:
+		return e.obj == Boolean{true}
+	}
+	return false
+}
+
+/*breaksOut returns true if a statement in the body of a loop can break out of it. Unlabeled breaks only leave
+  the loop while direct is true, that is when they aren't inside a nested loop */
+func breaksOut(stmt Stmt, label string, direct bool) bool {
+	switch s := stmt.(type) {
+	case LoopControl:
+		if s.keyword.Type != BREAK {
+			return false
+		}
+		if s.label == "" {
+			return direct
+		}
+		return s.label == label
+	case Block:
+		return anyBreaksOut(s.stmts, label, direct)
+	case If:
+		return breaksOut(s.ifTrue, label, direct) || s.ifFalse != nil && breaksOut(s.ifFalse, label, direct)
+	case While:
+		return breaksOut(s.body, label, false)
+	case For:
+		return breaksOut(s.body, label, false)
+	case ForIn:
+		return breaksOut(s.body, label, false)
+	case Try:
+		return anyBreaksOut(s.body, label, direct) || anyBreaksOut(s.catchBody, label, direct) || anyBreaksOut(s.finally, label, direct)
+	}
+	return false
+}
+
+func anyBreaksOut(stmts []Stmt, label string, direct bool) bool {
+	for _, stmt := range stmts {
+		if breaksOut(stmt, label, direct) {
+			return true
+		}
+	}
+	return false
+}
+
+/*checkReturn reports values which do not match the enclosing function's declared return type */
+func (c *Checker) checkReturn(r Return) {
+	if c.function == nil || c.result == nil {
+		if r.value != nil {
+			c.checkExpr(r.value)
+		}
+		return
+	}
+	var value *checkType = nilType
+	if r.value != nil {
+		value = c.checkAgainst(r.value, c.result)
+	}
+	if !assignable(c.result, value) {
+		c.errorAt(r.Position(), "'%s' must return a value of type %s, not %s", c.function.name.literal, c.result, value)
+	}
+}
+
+/*checkExpr infers the type of an expression, reporting any type errors within it */
+func (c *Checker) checkExpr(e Expr) *checkType {
+	switch e := e.(type) {
+	case Literal:
+		switch e.obj.(type) {
+		case Integer:
+			return intType
+		case Float:
+			return floatType
+		case Boolean:
+			return boolType
+		case String:
+			return stringType
+		}
+		return nilType
+	case Variable:
+		t, ok := c.scope.lookup(e.identifier.literal)
+		if !ok {
+			panic("checker: no type for variable '" + e.identifier.literal + "', which the resolver should have reported")
+		}
+		return t
+	case Assign:
+		target, ok := c.scope.lookup(e.identifier.literal)
+		if !ok {
+			target = anyType
+		}
+		value := c.checkAgainst(e.initializer, target)
+		if !assignable(target, value) {
+			c.errorAt(e.initializer.Position(), "cannot assign value of type %s to %s variable '%s'", value, target, e.identifier.literal)
+		}
+		return nilType
+	case Grouping:
+		return c.checkExpr(e.expr)
+	case Binary:
+		return c.checkBinary(e)
+	case Logical:
+		for _, side := range []Expr{e.left, e.right} {
+			if t := c.checkExpr(side); !assignable(boolType, t) {
+				c.errorAt(side.Position(), "cannot use value of type %s with '%s'", t, operatorString(e.operator))
+			}
+		}
+		return boolType
+	case Unary:
+		right := c.checkExpr(e.right)
+		if e.operator.Type == BANG {
+			if !assignable(boolType, right) {
+				c.errorAt(e.right.Position(), "cannot negate value of type %s", right)
+			}
+			return boolType
+		}
+		if right.kind != kindAny && !right.isNumber() {
+			c.errorAt(e.right.Position(), "cannot have negative value of type %s", right)
+			return anyType
+		}
+		return right
+	case Call:
+		return c.checkCall(e)
+	case Interpolation:
+		for _, part := range e.parts {
+			c.checkExpr(part)
+		}
+		return stringType
+	case ListLiteral:
+		elements := make([]*checkType, len(e.elements))
+		for idx, element := range e.elements {
+			elements[idx] = c.checkExpr(element)
+		}
+		return listOf(c.unify(e.elements, elements, "elements"))
+	case MapLiteral:
+		keys := make([]*checkType, len(e.keys))
+		values := make([]*checkType, len(e.values))
+		for idx := range e.keys {
+			keys[idx] = c.checkKey(e.keys[idx])
+			values[idx] = c.checkExpr(e.values[idx])
+		}
+		return mapOf(c.unify(e.keys, keys, "keys"), c.unify(e.values, values, "values"))
+	case Index:
+		return c.checkIndex(e.object, e.index)
+	case SetIndex:
+		target := c.checkIndex(e.object, e.index)
+		value := c.checkAgainst(e.value, target)
+		if !assignable(target, value) {
+			c.errorAt(e.value.Position(), "cannot assign value of type %s to element of type %s", value, target)
+		}
+		return nilType
+	case Slice:
+		object := c.checkExpr(e.object)
+		for _, bound := range []Expr{e.start, e.end} {
+			if bound != nil {
+				c.checkInt(bound, "slice bound")
+			}
+		}
+		if object.kind != kindList && object.kind != kindAny {
+			c.errorAt(e.object.Position(), "cannot slice value of type %s", object)
+			return anyType
+		}
+		return object
+	case Get:
+		return c.checkGet(e)
+	case Set:
+		target := c.checkGet(Get{e.object, e.name})
+		value := c.checkAgainst(e.value, target)
+		if !assignable(target, value) {
+			c.errorAt(e.value.Position(), "cannot assign value of type %s to %s field '%s'", value, target, e.name.literal)
+		}
+		return nilType
+	}
+	return anyType
+}
+
+/*checkAgainst infers the type of an expression whose value is stored where target is expected. The entries of
+  list and map literals are checked against the target's element types one by one, so a literal which mixes
+  types is reported where the wrong entry is */
+func (c *Checker) checkAgainst(e Expr, target *checkType) *checkType {
+	switch e := e.(type) {
+	case ListLiteral:
+		if target.kind == kindList {
+			for _, element := range e.elements {
+				c.checkEntry(element, target.params[0], "list element")
+			}
+			return target
+		}
+	case MapLiteral:
+		if target.kind == kindMap {
+			for idx := range e.keys {
+				c.checkEntry(e.keys[idx], target.params[0], "map key")
+				c.checkEntry(e.values[idx], target.params[1], "map value")
+			}
+			return target
+		}
+	}
+	return c.checkExpr(e)
+}
+
+/*checkEntry reports an entry of a literal which doesn't match the type the literal is declared with */
+func (c *Checker) checkEntry(e Expr, target *checkType, what string) {
+	if t := c.checkAgainst(e, target); !assignable(target, t) {
+		c.errorAt(e.Position(), "%s must be of type %s, not %s", what, target, t)
+	}
+}
+
+/*checkBinary infers the result of an arithmetic, comparison or membership operator */
+func (c *Checker) checkBinary(b Binary) *checkType {
+	left := c.checkExpr(b.left)
+	right := c.checkExpr(b.right)
+	op := b.operator.Type
+	comparison := op == EQUALEQUAL || op == BANGEQUAL || op == GREATER || op == GREATEREQUAL || op == LESS || op == LESSEQUAL
+	if op == IN {
+		c.checkMembership(b, left, right)
+		return boolType
+	}
+	if left.kind == kindAny || right.kind == kindAny {
+		if comparison {
+			return boolType
+		}
+		if left.kind == kindString && op == PLUS {
+			return stringType
+		}
+		return anyType
+	}
+	switch {
+	case left.isNumber() && right.isNumber():
+		if comparison {
+			return boolType
+		}
+		if left.kind == kindInt && right.kind == kindInt {
+			return intType
+		}
+		if op == MOD {
+			c.errorAt(b.operator.pos, "unsupported operation '%s' on values of type float", operatorString(b.operator))
+			return anyType
+		}
+		return floatType
+	case left.kind == kindBool && right.kind == kindBool:
+		if op != EQUALEQUAL && op != BANGEQUAL {
+			c.errorAt(b.operator.pos, "unsupported operation '%s' on values of type bool", operatorString(b.operator))
+		}
+		return boolType
+	case left.kind == kindString:
+		if op != PLUS {
+			c.errorAt(b.operator.pos, "string does not support '%s' operator", operatorString(b.operator))
+			return anyType
+		}
+		return stringType
+	}
+	c.errorAt(b.operator.pos, "mismatched operands of type %s and %s for '%s'", left, right, operatorString(b.operator))
+	return anyType
+}
+
+/*checkMembership reports in operators whose container doesn't support them */
+func (c *Checker) checkMembership(b Binary, left *checkType, right *checkType) {
+	switch right.kind {
+	case kindList, kindAny:
+	case kindMap:
+		c.checkHashable(b.left.Position(), left)
+	case kindString:
+		if !assignable(stringType, left) {
+			c.errorAt(b.left.Position(), "'in <string>' requires a string on the left, not %s", left)
+		}
+	default:
+		c.errorAt(b.right.Position(), "'in' is not supported for values of type %s", right)
+	}
+}
+
+/*checkKey infers the type of a map key, reporting keys which can't be hashed */
+func (c *Checker) checkKey(key Expr) *checkType {
+	t := c.checkExpr(key)
+	c.checkHashable(key.Position(), t)
+	return t
+}
+
+func (c *Checker) checkHashable(pos Position, t *checkType) {
+	switch t.kind {
+	case kindAny, kindInt, kindFloat, kindString, kindBool:
+		return
+	}
+	c.errorAt(pos, "unhashable map key of type %s", t)
+}
+
+/*checkInt reports expressions which must be ints but aren't */
+func (c *Checker) checkInt(e Expr, name string) {
+	if t := c.checkExpr(e); !assignable(intType, t) {
+		c.errorAt(e.Position(), "%s must be an int, not %s", name, t)
+	}
+}
+
+/*checkIndex infers the type of a list element or map value being looked up */
+func (c *Checker) checkIndex(object Expr, index Expr) *checkType {
+	container := c.checkExpr(object)
+	switch container.kind {
+	case kindList:
+		c.checkInt(index, "list index")
+		return container.params[0]
+	case kindMap:
+		key := c.checkKey(index)
+		if !assignable(container.params[0], key) {
+			c.errorAt(index.Position(), "map key must be of type %s, not %s", container.params[0], key)
+		}
+		return container.params[1]
+	case kindAny:
+		c.checkExpr(index)
+		return anyType
+	}
+	c.checkExpr(index)
+	c.errorAt(object.Position(), "cannot index value of type %s", container)
+	return anyType
+}
+
+/*checkGet infers the type of a struct field or method, or a property of a caught error */
+func (c *Checker) checkGet(g Get) *checkType {
+	object := c.checkExpr(g.object)
+	switch object.kind {
+	case kindAny:
+		return anyType
+	case kindInstance:
+		info := c.structs[object.name]
+		if field, ok := info.fields[g.name.literal]; ok {
+			return field
+		}
+		if method, ok := info.methods[g.name.literal]; ok {
+			return method
+		}
+		c.errorAt(g.name.pos, "'%s' has no field or method '%s'", object.name, g.name.literal)
+		return anyType
+	case kindError:
+		switch g.name.literal {
+		case "message", "kind", "file":
+			return stringType
+		case "line", "column":
+			return intType
+		}
+		c.errorAt(g.name.pos, "Error has no property '%s'", g.name.literal)
+		return anyType
+	}
+	c.errorAt(g.name.pos, "cannot access property '%s' on value of type %s", g.name.literal, object)
+	return anyType
+}
+
+/*checkCall checks the arguments of a call against the parameters of the function, struct or builtin being called */
+func (c *Checker) checkCall(call Call) *checkType {
+	callee := c.checkExpr(call.callee)
+	var params []*checkType
+	switch callee.kind {
+	case kindFunction:
+		params = callee.params
+	case kindStruct:
+		info := c.structs[callee.name]
+		params = make([]*checkType, len(info.declaration.fields))
+		for idx, field := range info.declaration.fields {
+			params[idx] = info.fields[field.name.literal]
+		}
+	}
+	args := make([]*checkType, len(call.args))
+	for idx, arg := range call.args {
+		if len(params) == len(call.args) {
+			args[idx] = c.checkAgainst(arg, params[idx])
+		} else {
+			args[idx] = c.checkExpr(arg)
+		}
+	}
+	switch callee.kind {
+	case kindAny:
+		return anyType
+	case kindBuiltin:
+		return c.checkBuiltin(call, callee.name, args)
+	case kindFunction:
+		if c.checkArguments(call, params, args) && callee.result != nil {
+			return callee.result
+		}
+		return anyType
+	case kindStruct:
+		c.checkArguments(call, params, args)
+		return &checkType{kind: kindInstance, name: callee.name}
+	}
+	c.errorAt(call.callee.Position(), "cannot call value of type %s", callee)
+	return anyType
+}
+
+/*checkArguments reports calls with the wrong number of arguments or arguments of the wrong type, returning
+  false if the number of arguments is wrong */
+func (c *Checker) checkArguments(call Call, params []*checkType, args []*checkType) bool {
+	if len(params) != len(args) {
+		c.errorAt(call.paren.pos, "expected %d arguments but got %d", len(params), len(args))
+		return false
+	}
+	for idx := range params {
+		if !assignable(params[idx], args[idx]) {
+			c.errorAt(call.args[idx].Position(), "argument %d must be of type %s, not %s", idx+1, params[idx], args[idx])
+		}
+	}
+	return true
+}
+
+/*checkBuiltin checks a call to one of the builtins, whose parameter types depend on their arguments */
+func (c *Checker) checkBuiltin(call Call, name string, args []*checkType) *checkType {
+	var arity int
+	for _, builtin := range builtins {
+		if builtin.name == name {
+			arity = builtin.arity
+		}
+	}
+	if len(args) != arity {
+		c.errorAt(call.paren.pos, "expected %d arguments but got %d", arity, len(args))
+		return anyType
+	}
+	first := args[0]
+	switch name {
+	case "len":
+		switch first.kind {
+		case kindAny, kindList, kindMap, kindString:
+		default:
+			c.errorAt(call.args[0].Position(), "len() expects a list, map or string, not %s", first)
+		}
+		return intType
+	case "append":
+		if first.kind == kindAny {
+			return anyType
+		}
+		if first.kind != kindList {
+			c.errorAt(call.args[0].Position(), "append() expects a list, not %s", first)
+			return anyType
+		}
+		if !assignable(first.params[0], args[1]) {
+			c.errorAt(call.args[1].Position(), "cannot append value of type %s to %s", args[1], first)
+		}
+		return first
+	case "delete", "keys", "values":
+		if first.kind == kindAny {
+			return anyType
+		}
+		if first.kind != kindMap {
+			c.errorAt(call.args[0].Position(), "%s() expects a map, not %s", name, first)
+			return anyType
+		}
+		switch name {
+		case "delete":
+			c.checkHashable(call.args[1].Position(), args[1])
+			return boolType
+		case "keys":
+			return listOf(first.params[0])
+		default:
+			return listOf(first.params[1])
+		}
+	}
+	return anyType
+}
@@ -0,0 +1,313 @@
+package butter
+
+import (
+	"strings"
+	"testing"
+)
+
+/*typeErrorTests are programs the checker rejects before they run, along with every error each one reports */
+var typeErrorTests = []struct {
+	name   string
+	source string
+	err    string
+}{
+	{"declaration mismatch", `
+int x := "hi"
+`, "TYPE_ERROR [test:2:10]: cannot assign value of type string to int variable 'x'"},
+	{"inferred declarations", `
+var a := 1
+a := "x"
+var xs := [1]
+xs[0] := 2.5
+struct P {int a}
+let p := P(1)
+p := 5
+`, strings.Join([]string{
+		"TYPE_ERROR [test:3:6]: cannot assign value of type string to int variable 'a'",
+		"TYPE_ERROR [test:5:10]: cannot assign value of type float to element of type int",
+		"TYPE_ERROR [test:8:6]: cannot assign value of type int to P variable 'p'",
+	}, "\n")},
+	{"empty collections", `
+var xs := []
+let m := {}
+`, strings.Join([]string{
+		"TYPE_ERROR [test:2:11]: cannot infer the type of 'xs' from an empty or mixed collection, declare its type instead",
+		"TYPE_ERROR [test:3:10]: cannot infer the type of 'm' from an empty or mixed collection, declare its type instead",
+	}, "\n")},
+	{"nested empty collections", `
+var ys := [[1], []]
+var m := {"a": {}}
+`, strings.Join([]string{
+		"TYPE_ERROR [test:2:11]: cannot infer the type of 'ys' from an empty or mixed collection, declare its type instead",
+		"TYPE_ERROR [test:3:10]: cannot infer the type of 'm' from an empty or mixed collection, declare its type instead",
+	}, "\n")},
+	{"mixed collection", `
+var xs := [1, "a"]
+`, "TYPE_ERROR [test:2:15]: cannot mix elements of type int and string in one literal"},
+	{"assignment mismatch", `
+float f := 1.5
+f := true
+`, "TYPE_ERROR [test:3:6]: cannot assign value of type bool to float variable 'f'"},
+	{"string operator", `
+print "a" - 1
+`, "TYPE_ERROR [test:2:11]: string does not support '-' operator"},
+	{"mismatched operands", `
+print 1 + true
+`, "TYPE_ERROR [test:2:9]: mismatched operands of type int and bool for '+'"},
+	{"float modulo", `
+print 1.5 % 2
+`, "TYPE_ERROR [test:2:11]: unsupported operation '%' on values of type float"},
+	{"bool arithmetic", `
+print true < false
+`, "TYPE_ERROR [test:2:12]: unsupported operation '<' on values of type bool"},
+	{"logical operands", `
+print 1 and true
+print true or "a"
+`, strings.Join([]string{
+		"TYPE_ERROR [test:2:7]: cannot use value of type int with 'and'",
+		"TYPE_ERROR [test:3:15]: cannot use value of type string with 'or'",
+	}, "\n")},
+	{"negated string", `
+print -"a"
+`, "TYPE_ERROR [test:2:8]: cannot have negative value of type string"},
+	{"condition", `
+if 1 {
+}
+`, "TYPE_ERROR [test:2:4]: cannot use value of type int in if conditional"},
+	{"call arity", `
+fn add(int a, int b) int {
+  return a + b
+}
+print add(1)
+`, "TYPE_ERROR [test:5:12]: expected 2 arguments but got 1"},
+	{"argument types", `
+fn add(int a, int b) int {
+  return a + b
+}
+print add(1, "2")
+`, "TYPE_ERROR [test:5:14]: argument 2 must be of type int, not string"},
+	{"builtin arguments", `
+print len(5)
+`, "TYPE_ERROR [test:2:11]: len() expects a list, map or string, not int"},
+	{"struct constructor", `
+struct P {int a; string b}
+P p := P("a", "b")
+`, "TYPE_ERROR [test:3:10]: argument 1 must be of type int, not string"},
+	{"struct fields and methods", `
+struct P {int a}
+fn (P p) double() int {
+  return p.a * 2
+}
+P p := P(1)
+p.a := "x"
+print p.b
+print p.double(1)
+`, strings.Join([]string{
+		"TYPE_ERROR [test:7:8]: cannot assign value of type string to int field 'a'",
+		"TYPE_ERROR [test:8:9]: 'P' has no field or method 'b'",
+		"TYPE_ERROR [test:9:17]: expected 0 arguments but got 1",
+	}, "\n")},
+	{"struct declarations", `
+struct P {int a}
+fn (P p) f() {
+}
+fn (P p) f() {
+}
+fn (P p) a() {
+}
+fn (Q q) g() {
+}
+print P(1) == P(1)
+`, strings.Join([]string{
+		"TYPE_ERROR [test:5:10]: 'P' already has a method named 'f'",
+		"TYPE_ERROR [test:7:10]: 'P' already has a field named 'a'",
+		"TYPE_ERROR [test:9:5]: unknown type 'Q'",
+		"TYPE_ERROR [test:11:12]: mismatched operands of type P and P for '=='",
+	}, "\n")},
+	{"unknown type", `
+Q q := 1
+`, "TYPE_ERROR [test:2:1]: unknown type 'Q'"},
+	{"unknown type in a signature", `
+fn f(Q q) R {
+  return q
+}
+`, strings.Join([]string{
+		"TYPE_ERROR [test:2:6]: unknown type 'Q'",
+		"TYPE_ERROR [test:2:11]: unknown type 'R'",
+	}, "\n")},
+	{"collections", `
+list<int> xs := [1, 2]
+map<string, int> m := {"a": 1}
+xs[0] := "x"
+print m[1]
+print xs["a"]
+`, strings.Join([]string{
+		"TYPE_ERROR [test:4:10]: cannot assign value of type string to element of type int",
+		"TYPE_ERROR [test:5:9]: map key must be of type string, not int",
+		"TYPE_ERROR [test:6:10]: list index must be an int, not string",
+	}, "\n")},
+	{"list operations", `
+list<int> xs := [1]
+append(xs, "a")
+print [1, 2] == [1, 2]
+print "a"[0]
+print xs[1.5:]
+`, strings.Join([]string{
+		"TYPE_ERROR [test:3:12]: cannot append value of type string to list<int>",
+		"TYPE_ERROR [test:4:14]: mismatched operands of type list<int> and list<int> for '=='",
+		"TYPE_ERROR [test:5:7]: cannot index value of type string",
+		"TYPE_ERROR [test:6:10]: slice bound must be an int, not float",
+	}, "\n")},
+	{"map keys", `
+fn k() list<int> {
+  return [1]
+}
+map<int, int> m := {}
+print k() in m
+print {[1]: 2}
+`, strings.Join([]string{
+		"TYPE_ERROR [test:6:7]: unhashable map key of type list<int>",
+		"TYPE_ERROR [test:7:8]: unhashable map key of type list<int>",
+	}, "\n")},
+	{"literal entries", `
+list<int> xs := [1, "a"]
+map<string, int> m := {"a": 1, 2: "b"}
+list<list<int>> nested := [[1], [2.5]]
+`, strings.Join([]string{
+		"TYPE_ERROR [test:2:21]: list element must be of type int, not string",
+		"TYPE_ERROR [test:3:32]: map key must be of type string, not int",
+		"TYPE_ERROR [test:3:35]: map value must be of type int, not string",
+		"TYPE_ERROR [test:4:34]: list element must be of type int, not float",
+	}, "\n")},
+	{"literal arguments", `
+fn sum(list<int> xs) int {
+  return len(xs)
+}
+print sum([1, true])
+`, "TYPE_ERROR [test:5:15]: list element must be of type int, not bool"},
+	{"mixed literal", `
+print [1, "a"]
+print {1: "a", "b": "c"}
+`, strings.Join([]string{
+		"TYPE_ERROR [test:2:11]: cannot mix elements of type int and string in one literal",
+		"TYPE_ERROR [test:3:16]: cannot mix keys of type int and string in one literal",
+	}, "\n")},
+	{"untaken branch", `
+if false {
+  print "a" - 1
+}
+`, "TYPE_ERROR [test:3:13]: string does not support '-' operator"},
+	{"function never called", `
+fn f() {
+  int x := true
+}
+`, "TYPE_ERROR [test:3:12]: cannot assign value of type bool to int variable 'x'"},
+	{"return type", `
+fn f() int {
+  return "a"
+}
+`, "TYPE_ERROR [test:3:3]: 'f' must return a value of type int, not string"},
+	{"missing return", `
+fn f(int n) int {
+  if n > 0 {
+    return 1
+  }
+}
+fn g() int {
+  outer: while true {
+    while true {
+      break outer
+    }
+  }
+}
+`, strings.Join([]string{
+		"TYPE_ERROR [test:2:4]: 'f' must return a value of type int, but can finish without returning",
+		"TYPE_ERROR [test:7:4]: 'g' must return a value of type int, but can finish without returning",
+	}, "\n")},
+	{"global declared after the function using it", `
+fn later() int {
+  return y
+}
+string y := "s"
+`, "TYPE_ERROR [test:3:3]: 'later' must return a value of type int, not string"},
+}
+
+func TestTypeErrors(t *testing.T) {
+	for _, test := range typeErrorTests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := errorString(New().CheckSource(NewSource("test", test.source))); got != test.err {
+				t.Errorf("check failed with\n%s\nwant\n%s", got, test.err)
+			}
+			for _, bytecode := range []bool{false, true} {
+				output, err := runConformance(test.source, bytecode)
+				if output != "" {
+					t.Errorf("printed %q before the error was reported", output)
+				}
+				if _, ok := err.(TypeCheckErrors); !ok {
+					t.Errorf("running failed with %v, want TypeCheckErrors", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckAccepts(t *testing.T) {
+	source := `
+fn later() int {
+  return y + 1
+}
+int y := 7
+{
+  fn early() string {
+    return z
+  }
+  string z := "z"
+  print early()
+}
+fn sign(int n) int {
+  if n < 0 {
+    return -1
+  } else if n == 0 {
+    return 0
+  }
+  return 1
+}
+fn forever() int {
+  while true {
+    for int i := 0; i < 3; i := i + 1 {
+      break
+    }
+    return 2
+  }
+}
+fn attempt() int {
+  try {
+    throw "no"
+  } catch (e) {
+    return 3
+  }
+}
+print later()
+print sign(-5) + forever() + attempt()
+`
+	if err := New().CheckSource(NewSource("test", source)); err != nil {
+		t.Fatalf("check failed with %v", err)
+	}
+	output, err := runConformance(source, false)
+	if err != nil || output != "z\n8\n4\n" {
+		t.Errorf("printed %q and failed with %v", output, err)
+	}
+}
+
+func TestCheckerUnresolvedVariable(t *testing.T) {
+	stmts, err := Parse(NewSource("test", "print zz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "'zz'") {
+			t.Errorf("checking an unresolved variable recovered %v, want the checker to panic", r)
+		}
+	}()
+	Check(stmts)
+}
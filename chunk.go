@@ -0,0 +1,189 @@
+package butter
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*Opcode is a single bytecode instruction. Operands follow the opcode in the chunk, most are two bytes wide */
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpNil
+	OpTrue
+	OpFalse
+	OpPop
+	OpPrint
+	OpEcho
+	OpDefineGlobal
+	OpGetGlobal
+	OpSetGlobal
+	OpDeclareLocal
+	OpGetLocal
+	OpSetLocal
+	OpGetUpvalue
+	OpSetUpvalue
+	OpCloseUpvalue
+	OpBinary
+	OpUnary
+	OpLogical
+	OpCheckBool
+	OpJump
+	OpJumpIfFalse
+	OpLoop
+	OpCall
+	OpClosure
+	OpReturn
+	OpList
+	OpMap
+	OpGetIndex
+	OpSetIndex
+	OpSlice
+	OpGetProperty
+	OpSetProperty
+	OpStruct
+	OpMethod
+	OpStringify
+	OpFormat
+	OpConcat
+	OpThrow
+	OpTry
+	OpEndTry
+	OpIterStart
+	OpIterNext
+)
+
+/*opcodeNames are the names of each opcode, used when disassembling a chunk */
+var opcodeNames = [...]string{
+	"CONSTANT", "NIL", "TRUE", "FALSE", "POP", "PRINT", "ECHO", "DEFINE_GLOBAL", "GET_GLOBAL",
+	"SET_GLOBAL", "DECLARE_LOCAL", "GET_LOCAL", "SET_LOCAL", "GET_UPVALUE", "SET_UPVALUE", "CLOSE_UPVALUE", "BINARY", "UNARY",
+	"LOGICAL", "CHECK_BOOL", "JUMP", "JUMP_IF_FALSE", "LOOP", "CALL", "CLOSURE", "RETURN", "LIST", "MAP",
+	"GET_INDEX", "SET_INDEX", "SLICE", "GET_PROPERTY", "SET_PROPERTY", "STRUCT", "METHOD", "STRINGIFY", "FORMAT",
+	"CONCAT", "THROW", "TRY", "END_TRY", "ITER_START", "ITER_NEXT",
+}
+
+func (op Opcode) String() string {
+	if int(op) < len(opcodeNames) {
+		return opcodeNames[op]
+	}
+	return fmt.Sprintf("OP_%d", op)
+}
+
+/*operandWidths are the sizes in bytes of the operands which follow each opcode */
+var operandWidths = map[Opcode][]int{
+	OpConstant:     {2},
+	OpDefineGlobal: {2, 2},
+	OpGetGlobal:    {2},
+	OpSetGlobal:    {2},
+	OpDeclareLocal: {2, 2, 2},
+	OpGetLocal:     {2},
+	OpSetLocal:     {2},
+	OpGetUpvalue:   {2},
+	OpSetUpvalue:   {2},
+	OpBinary:       {1},
+	OpUnary:        {1},
+	OpLogical:      {1, 2},
+	OpCheckBool:    {1},
+	OpJump:         {2},
+	OpJumpIfFalse:  {1, 2},
+	OpLoop:         {2},
+	OpCall:         {1},
+	OpClosure:      {2},
+	OpList:         {2},
+	OpMap:          {2},
+	OpSlice:        {1},
+	OpGetProperty:  {2},
+	OpSetProperty:  {2},
+	OpStruct:       {2},
+	OpMethod:       {2},
+	OpFormat:       {2},
+	OpConcat:       {2},
+	OpTry:          {2},
+	OpIterStart:    {1},
+	OpIterNext:     {2, 1, 2},
+}
+
+/*Declared types are referred to by their index in a chunk's type table, apart from these two markers */
+const (
+	typeUntyped  = 0xFFFF
+	typeInferred = 0xFFFE
+)
+
+/*Chunk is a compiled sequence of bytecode along with the constants and declared types it refers to. Every
+  byte records the position in the source it was compiled from, so errors can report where they happened */
+type Chunk struct {
+	code      []byte
+	positions []Position
+	constants []Object
+	types     []TypeSpec
+}
+
+/*write appends a byte compiled from the passed position */
+func (c *Chunk) write(b byte, pos Position) {
+	c.code = append(c.code, b)
+	c.positions = append(c.positions, pos)
+}
+
+/*addConstant returns the index of a value in the constant pool, adding it if it isn't already there */
+func (c *Chunk) addConstant(value Object) int {
+	switch value.(type) {
+	case Integer, Float, String, Boolean:
+		for idx, constant := range c.constants {
+			if constant == value {
+				return idx
+			}
+		}
+	}
+	c.constants = append(c.constants, value)
+	return len(c.constants) - 1
+}
+
+/*addType returns the index of a declared type in the type table */
+func (c *Chunk) addType(varType TypeSpec) int {
+	for idx, existing := range c.types {
+		if existing.Equals(varType) {
+			return idx
+		}
+	}
+	c.types = append(c.types, varType)
+	return len(c.types) - 1
+}
+
+func (c *Chunk) readShort(offset int) int {
+	return int(c.code[offset])<<8 | int(c.code[offset+1])
+}
+
+/*Disassemble returns a listing of every instruction in the chunk along with its operands, followed by the
+  listings of any functions it declares */
+func (c *Chunk) Disassemble(name string) string {
+	var builder strings.Builder
+	var functions []*CompiledFunction
+	builder.WriteString("== " + name + " ==\n")
+	for offset := 0; offset < len(c.code); {
+		op := Opcode(c.code[offset])
+		builder.WriteString(fmt.Sprintf("%04d %4d %-14s", offset, c.positions[offset].Line, op))
+		offset++
+		for idx, width := range operandWidths[op] {
+			operand := int(c.code[offset])
+			if width == 2 {
+				operand = c.readShort(offset)
+			}
+			offset += width
+			builder.WriteString(fmt.Sprintf(" %d", operand))
+			if idx == 0 && (op == OpConstant || op == OpClosure || op == OpStruct) {
+				builder.WriteString(" (" + Inspect(c.constants[operand]) + ")")
+			}
+		}
+		if op == OpClosure {
+			function := c.constants[c.readShort(offset-2)].(*CompiledFunction)
+			functions = append(functions, function)
+			offset += 3 * function.upvalueCount
+		}
+		builder.WriteString("\n")
+	}
+	for _, function := range functions {
+		builder.WriteString(function.chunk.Disassemble(function.name))
+	}
+	return builder.String()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/abrahampost/Butter"
+)
+
+func TestSettingsParse(t *testing.T) {
+	tests := []struct {
+		args []string
+		want Settings
+	}{
+		{nil, Settings{command: "repl"}},
+		{[]string{"--vm"}, Settings{command: "repl", bytecode: true}},
+		{[]string{"prog.btr"}, Settings{command: "run", fileLoc: "prog.btr"}},
+		{[]string{"--vm", "prog.btr", "a", "--vm"}, Settings{command: "run", bytecode: true, fileLoc: "prog.btr", args: []string{"a", "--vm"}}},
+		{[]string{"run", "-", "a"}, Settings{command: "run", fileLoc: "-", args: []string{"a"}}},
+		{[]string{"-e", "print 1", "a", "b"}, Settings{command: "run", expr: "print 1", fromExpr: true, args: []string{"a", "b"}}},
+		{[]string{"check", "prog.btr"}, Settings{command: "check", fileLoc: "prog.btr"}},
+		{[]string{"fmt", "-w", "prog.btr"}, Settings{command: "fmt", write: true, fileLoc: "prog.btr"}},
+		{[]string{"ast", "-e", "1"}, Settings{command: "ast", expr: "1", fromExpr: true}},
+		{[]string{"--", "check"}, Settings{command: "run", fileLoc: "check"}},
+		{[]string{"run", "check"}, Settings{command: "run", fileLoc: "check"}},
+		{[]string{"version"}, Settings{command: "version"}},
+		{[]string{"--version"}, Settings{command: "version"}},
+		{[]string{"check", "--help"}, Settings{command: "help"}},
+	}
+	for _, test := range tests {
+		var settings Settings
+		if err := settings.Parse(test.args); err != nil {
+			t.Errorf("parsing %q: %v", test.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(settings, test.want) {
+			t.Errorf("parsing %q gave %+v, want %+v", test.args, settings, test.want)
+		}
+	}
+}
+
+func TestSettingsUsageErrors(t *testing.T) {
+	tests := [][]string{
+		{"--bogus"},
+		{"-e"},
+		{"check"},
+		{"tokens", "prog.btr", "extra"},
+		{"repl", "prog.btr"},
+		{"version", "-e", "1"},
+		{"-w", "prog.btr"},
+		{"fmt", "-w", "-"},
+	}
+	for _, args := range tests {
+		var settings Settings
+		err := settings.Parse(args)
+		if _, ok := err.(*UsageError); !ok {
+			t.Errorf("parsing %q returned %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	vm := butter.New()
+	_, parseErr := vm.Eval("print (")
+	_, typeErr := vm.Eval("int x := \"a\"")
+	_, runtimeErr := vm.Eval("print [1][2]")
+	_, fileErr := vm.EvalFile("no such file.btr")
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{runtimeErr, exitRuntime},
+		{usageError("bad"), exitUsage},
+		{parseErr, exitSyntax},
+		{typeErr, exitType},
+		{fileErr, exitFile},
+		{errors.New("other"), exitRuntime},
+	}
+	for _, test := range tests {
+		if got := exitCode(test.err); got != test.want {
+			t.Errorf("exit code for %v is %d, want %d", test.err, got, test.want)
+		}
+	}
+	if _, ok := fileErr.(*os.PathError); !ok {
+		t.Errorf("reading a missing file returned %T, want *os.PathError", fileErr)
+	}
+}
+
+func TestCheckExitCode(t *testing.T) {
+	vm = butter.New()
+	tests := []struct {
+		expr string
+		want int
+	}{
+		{"print 1 + 2", exitOK},
+		{"print [1][2]", exitOK},
+		{"if false {\n  print \"a\" - 1\n}", exitType},
+		{"fn f() int {\n}", exitType},
+		{"print (", exitSyntax},
+		{"print zz", exitSyntax},
+	}
+	for _, test := range tests {
+		err := RunProgram(Settings{command: "check", expr: test.expr, fromExpr: true})
+		if got := exitCode(err); got != test.want {
+			t.Errorf("checking %q exited with %d (%v), want %d", test.expr, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/abrahampost/Butter"
+)
+
+/*Command is a REPL command, entered as a colon followed by its name and, for most commands, an argument.
+  Commands which take source carry on over several lines while it is incomplete, like any other input */
+type Command struct {
+	name   string
+	usage  string
+	help   string
+	source bool
+	run    func(arg string) error
+}
+
+var commands []Command
+
+func init() {
+	commands = []Command{
+		{"env", "", "list the variables, functions and structs defined so far", false, EnvCommand},
+		{"type", "<expr>", "show the type of an expression without running it", true, TypeCommand},
+		{"load", "<file>", "run a file, keeping what it defines in the session", false, LoadCommand},
+		{"reset", "", "discard everything defined so far", false, ResetCommand},
+		{"ast", "<source>", "show the syntax tree source is parsed into", true, AstCommand},
+		{"tokens", "<source>", "show the tokens source is split into", true, TokensCommand},
+		{"time", "<source>", "run source and show how long it took", true, TimeCommand},
+		{"help", "", "list the REPL commands", false, HelpCommand},
+	}
+}
+
+/*IsCommand returns true if a line entered at the REPL is a command rather than source */
+func IsCommand(input string) bool {
+	return strings.HasPrefix(strings.TrimSpace(input), ":")
+}
+
+/*ParseCommand splits a line of REPL input into the command it names and the argument after it */
+func ParseCommand(input string) (*Command, string) {
+	name, arg := strings.TrimSpace(input)[1:], ""
+	if space := strings.IndexAny(name, " \t\n"); space != -1 {
+		name, arg = name[:space], strings.TrimSpace(name[space:])
+	}
+	for idx := range commands {
+		if commands[idx].name == name {
+			return &commands[idx], arg
+		}
+	}
+	return &Command{name: name}, arg
+}
+
+/*CommandIncomplete returns true if a command takes source which has been left unfinished */
+func CommandIncomplete(input string) bool {
+	command, arg := ParseCommand(input)
+	return command.source && butter.Incomplete(arg+"\n")
+}
+
+/*RunCommand runs a REPL command, reporting any error without ending the session */
+func RunCommand(input string) {
+	command, arg := ParseCommand(input)
+	switch {
+	case command.run == nil:
+		fmt.Fprintf(os.Stderr, "Unknown command ':%s', enter :help for a list of commands\n", command.name)
+	case command.usage != "" && arg == "":
+		fmt.Fprintf(os.Stderr, "Usage: :%s %s\n", command.name, command.usage)
+	default:
+		if err := command.run(arg); err != nil {
+			fmt.Fprintln(os.Stderr, butter.FormatError(err))
+		}
+	}
+}
+
+/*EnvCommand lists every global along with its type and value */
+func EnvCommand(arg string) error {
+	for _, global := range vm.Globals() {
+		fmt.Printf("%s: %s = %s\n", global.Name, global.Type, butter.Stringify(global.Value))
+	}
+	return nil
+}
+
+/*TypeCommand prints the static type of an expression */
+func TypeCommand(arg string) error {
+	exprType, err := vm.TypeOf(arg)
+	if err != nil {
+		return err
+	}
+	fmt.Println(exprType)
+	return nil
+}
+
+/*LoadCommand runs a file in the current session, without printing the value of its expressions */
+func LoadCommand(arg string) error {
+	vm.UseREPL(false)
+	defer vm.UseREPL(true)
+	_, err := vm.EvalFile(arg)
+	return err
+}
+
+/*ResetCommand discards every global, starting the session afresh */
+func ResetCommand(arg string) error {
+	vm.Reset()
+	vm.UseREPL(true)
+	return nil
+}
+
+/*AstCommand prints each statement the source is parsed into, one per line */
+func AstCommand(arg string) error {
+	return ShowAst(butter.NewSource("<ast>", arg))
+}
+
+/*TokensCommand prints each token the source is split into */
+func TokensCommand(arg string) error {
+	return ShowTokens(butter.NewSource("<tokens>", arg))
+}
+
+/*ShowAst prints each statement of a program as a syntax tree, one per line */
+func ShowAst(source *butter.Source) error {
+	stmts, err := butter.Parse(source)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		fmt.Println(butter.StmtString(stmt))
+	}
+	return nil
+}
+
+/*ShowTokens prints each token a program is split into */
+func ShowTokens(source *butter.Source) error {
+	tokenizer := butter.NewTokenizer(source)
+	tokens := tokenizer.Tokenize()
+	if errs := tokenizer.Errors(); len(errs) > 0 {
+		return butter.ParseErrors(errs)
+	}
+	butter.PrintTokens(tokens)
+	return nil
+}
+
+/*TimeCommand runs the source like any other input, then prints how long it took */
+func TimeCommand(arg string) error {
+	start := time.Now()
+	_, err := vm.Eval(arg)
+	fmt.Printf("took %v\n", time.Since(start))
+	return err
+}
+
+/*HelpCommand lists the REPL commands */
+func HelpCommand(arg string) error {
+	for _, command := range commands {
+		usage := ":" + command.name
+		if command.usage != "" {
+			usage += " " + command.usage
+		}
+		fmt.Printf("  %-18s %s\n", usage, command.help)
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/abrahampost/Butter"
+)
+
+/*maxHistory is the number of lines of history kept between sessions */
+const maxHistory = 1000
+
+/*ErrInterrupted is returned by ReadLine when Ctrl-C abandons the line being edited */
+var ErrInterrupted = errors.New("interrupted")
+
+/*The control keys handled by the line editor, as the terminal sends them in raw mode */
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlJ     = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+/*Keys which the terminal sends as escape sequences are given negative codes, so they can't clash with runes */
+const (
+	keyUp rune = -(iota + 1)
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+/*LineEditor edits lines typed at a terminal in raw mode, handling cursor movement, history and completion
+  itself. Every line entered is added to the history, which can be saved for the next session */
+type LineEditor struct {
+	in  *bufio.Reader
+	out io.Writer
+	//complete returns the names which identifiers can be completed to
+	complete    func() []string
+	history     []string
+	historyFile string
+	prompt      string
+	line        []rune
+	cursor      int
+}
+
+/*NewLineEditor returns an editor reading keys from in and drawing the line being edited to out */
+func NewLineEditor(in io.Reader, out io.Writer, complete func() []string) *LineEditor {
+	return &LineEditor{in: bufio.NewReader(in), out: out, complete: complete}
+}
+
+/*LoadHistory reads the history saved by earlier sessions from path, which SaveHistory will write back to. A
+  missing file is an empty history */
+func (e *LineEditor) LoadHistory(path string) {
+	e.historyFile = path
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(text), "\n") {
+		e.AddHistory(line)
+	}
+}
+
+/*SaveHistory writes the most recent lines of history to the file they were loaded from */
+func (e *LineEditor) SaveHistory() error {
+	if e.historyFile == "" || len(e.history) == 0 {
+		return nil
+	}
+	return ioutil.WriteFile(e.historyFile, []byte(strings.Join(e.history, "\n")+"\n"), 0600)
+}
+
+/*AddHistory remembers a line, unless it is blank or the same as the line before it */
+func (e *LineEditor) AddHistory(line string) {
+	line = strings.TrimRight(line, " \t\r\n")
+	if strings.TrimSpace(line) == "" || len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+}
+
+/*ReadLine shows the prompt and lets a line be edited until Enter is pressed, returning it with a trailing
+  newline. Ctrl-D on an empty line returns io.EOF and Ctrl-C returns ErrInterrupted */
+func (e *LineEditor) ReadLine(prompt string) (string, error) {
+	e.prompt, e.line, e.cursor = prompt, nil, 0
+	//browsing the history starts just past the newest line, where the line being typed is kept
+	browsing, draft := len(e.history), ""
+	for {
+		e.refresh()
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+		switch key {
+		case keyEnter, keyCtrlJ:
+			return e.submit(), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				return "", io.EOF
+			}
+			e.deleteAt(e.cursor)
+		case keyBackspace, keyCtrlH:
+			if e.cursor > 0 {
+				e.cursor--
+				e.deleteAt(e.cursor)
+			}
+		case keyDelete:
+			e.deleteAt(e.cursor)
+		case keyLeft, keyCtrlB:
+			if e.cursor > 0 {
+				e.cursor--
+			}
+		case keyRight, keyCtrlF:
+			if e.cursor < len(e.line) {
+				e.cursor++
+			}
+		case keyHome, keyCtrlA:
+			e.cursor = 0
+		case keyEnd, keyCtrlE:
+			e.cursor = len(e.line)
+		case keyCtrlK:
+			e.line = e.line[:e.cursor]
+		case keyCtrlU:
+			e.line = append([]rune{}, e.line[e.cursor:]...)
+			e.cursor = 0
+		case keyCtrlW:
+			//delete back to the start of the previous word, along with any spaces after it
+			start := e.cursor
+			for start > 0 && unicode.IsSpace(e.line[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(e.line[start-1]) {
+				start--
+			}
+			e.line = append(e.line[:start], e.line[e.cursor:]...)
+			e.cursor = start
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyUp, keyCtrlP:
+			if browsing > 0 {
+				if browsing == len(e.history) {
+					draft = string(e.line)
+				}
+				browsing--
+				e.setLine(e.history[browsing])
+			}
+		case keyDown, keyCtrlN:
+			if browsing < len(e.history) {
+				browsing++
+				if browsing == len(e.history) {
+					e.setLine(draft)
+				} else {
+					e.setLine(e.history[browsing])
+				}
+			}
+		case keyTab:
+			e.completeWord()
+		case keyCtrlR:
+			accepted, err := e.search()
+			if err != nil {
+				return "", err
+			}
+			if accepted {
+				return e.submit(), nil
+			}
+		default:
+			if key >= ' ' {
+				e.insert(key)
+			}
+		}
+	}
+}
+
+/*submit finishes editing, adding the line to the history */
+func (e *LineEditor) submit() string {
+	e.cursor = len(e.line)
+	e.refresh()
+	fmt.Fprint(e.out, "\r\n")
+	line := string(e.line)
+	e.AddHistory(line)
+	return line + "\n"
+}
+
+/*readKey reads a single key, decoding the escape sequences sent for arrow and editing keys */
+func (e *LineEditor) readKey() (rune, error) {
+	key, _, err := e.in.ReadRune()
+	if err != nil || key != keyEscape {
+		return key, err
+	}
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+	var params []rune
+	for {
+		char, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		//sequences end with a character from @ to ~, anything before it is a parameter
+		if char >= '@' && char <= '~' {
+			return escapeKey(string(params), char), nil
+		}
+		params = append(params, char)
+	}
+}
+
+/*escapeKey returns the key an escape sequence stands for */
+func escapeKey(params string, final rune) rune {
+	switch final {
+	case 'A':
+		return keyUp
+	case 'B':
+		return keyDown
+	case 'C':
+		return keyRight
+	case 'D':
+		return keyLeft
+	case 'H':
+		return keyHome
+	case 'F':
+		return keyEnd
+	case '~':
+		switch params {
+		case "1", "7":
+			return keyHome
+		case "4", "8":
+			return keyEnd
+		case "3":
+			return keyDelete
+		}
+	}
+	return keyUnknown
+}
+
+func (e *LineEditor) insert(chars ...rune) {
+	line := make([]rune, 0, len(e.line)+len(chars))
+	line = append(line, e.line[:e.cursor]...)
+	line = append(line, chars...)
+	e.line = append(line, e.line[e.cursor:]...)
+	e.cursor += len(chars)
+}
+
+func (e *LineEditor) deleteAt(idx int) {
+	if idx < len(e.line) {
+		e.line = append(e.line[:idx], e.line[idx+1:]...)
+	}
+}
+
+func (e *LineEditor) setLine(line string) {
+	e.line = []rune(line)
+	e.cursor = len(e.line)
+}
+
+/*wordStart returns where the identifier ending at the cursor starts */
+func (e *LineEditor) wordStart() int {
+	start := e.cursor
+	for start > 0 && e.line[start-1] < utf8.RuneSelf && isIdentifierByte(byte(e.line[start-1])) {
+		start--
+	}
+	return start
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || butter.IsAlphaNum(c)
+}
+
+/*refresh redraws the prompt and the line being edited, then puts the terminal's cursor where the editor's is */
+func (e *LineEditor) refresh() {
+	e.draw(e.prompt, string(e.line), utf8.RuneCountInString(e.prompt)+e.cursor)
+}
+
+/*draw replaces the current terminal line with the prompt and text, moving the cursor to column */
+func (e *LineEditor) draw(prompt string, text string, column int) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K\r", prompt, text)
+	if column > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dC", column)
+	}
+}
+
+/*completeWord completes the identifier before the cursor. A single match is filled in, otherwise as much as
+  every match shares is, and the matches are listed once there is nothing more to fill in. With no identifier
+  before the cursor Tab indents instead */
+func (e *LineEditor) completeWord() {
+	prefix := string(e.line[e.wordStart():e.cursor])
+	if prefix == "" {
+		e.insert(' ', ' ')
+		return
+	}
+	var matches []string
+	for _, name := range e.complete() {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	if len(matches) == 0 {
+		return
+	}
+	common := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(common) > len(prefix) {
+		e.insert([]rune(common[len(prefix):])...)
+		return
+	}
+	if len(matches) > 1 {
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(matches, "  "))
+	}
+}
+
+/*search runs a reverse incremental search through the history. Typing narrows the search, Ctrl-R moves on to
+  the next older match and Enter runs the match. Any other key leaves the match on the line to be edited, while
+  Ctrl-C or Ctrl-G put back the line as it was. It returns true if the match should be run */
+func (e *LineEditor) search() (bool, error) {
+	var query []rune
+	match := len(e.history)
+	for {
+		found := ""
+		if match < len(e.history) {
+			found = e.history[match]
+		}
+		prompt := fmt.Sprintf("(reverse-i-search)`%s': ", string(query))
+		column := utf8.RuneCountInString(prompt)
+		if idx := strings.Index(found, string(query)); idx > 0 {
+			column += utf8.RuneCountInString(found[:idx])
+		}
+		e.draw(prompt, found, column)
+		key, err := e.readKey()
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case key == keyCtrlR:
+			if older := e.findHistory(string(query), match-1); older != len(e.history) {
+				match = older
+			}
+		case key == keyBackspace || key == keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				match = e.findHistory(string(query), len(e.history)-1)
+			}
+		case key == keyCtrlC || key == keyCtrlG:
+			return false, nil
+		case key >= ' ':
+			query = append(query, key)
+			from := match
+			if from == len(e.history) {
+				from--
+			}
+			match = e.findHistory(string(query), from)
+		default:
+			if found != "" {
+				e.setLine(found)
+			}
+			return key == keyEnter || key == keyCtrlJ, nil
+		}
+	}
+}
+
+/*findHistory returns the newest line of history at or before from which contains the query, or the length
+  of the history if there isn't one */
+func (e *LineEditor) findHistory(query string, from int) int {
+	for idx := from; idx >= 0; idx-- {
+		if strings.Contains(e.history[idx], query) {
+			return idx
+		}
+	}
+	return len(e.history)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*readLine types keys into an editor holding the given history and returns the line it reads */
+func readLine(t *testing.T, history []string, keys string) (string, error) {
+	t.Helper()
+	complete := func() []string { return []string{"print", "primes", "private", "total"} }
+	editor := NewLineEditor(strings.NewReader(keys), ioutil.Discard, complete)
+	for _, line := range history {
+		editor.AddHistory(line)
+	}
+	return editor.ReadLine("> ")
+}
+
+func TestLineEditing(t *testing.T) {
+	history := []string{"int x := 1", "print x + 1", "fn f() {"}
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"typing", "print 1\r", "print 1\n"},
+		{"backspace", "prinx\x7ft 2\r", "print 2\n"},
+		{"cursor movement", "print 3\x1b[D\x1b[D\x1b[D\x1b[C(\x05)\r", "print( 3)\n"},
+		{"home and end", "2\x01print \x1b[F + 1\r", "print 2 + 1\n"},
+		{"delete", "print 44\x1b[D\x1b[3~\r", "print 4\n"},
+		{"kill to end", "print 5 junk\x1b[D\x1b[D\x1b[D\x1b[D\x1b[D\x0b\r", "print 5\n"},
+		{"kill to start", "junk print 6\x01\x1b[C\x1b[C\x1b[C\x1b[C\x1b[C\x15\r", "print 6\n"},
+		{"delete word", "print 7 junk  \x17\r", "print 7 \n"},
+		{"unicode", "print \"héllo\"\x1b[D\x1b[D\x1b[D\x1b[D\x7f\x1b[F\r", "print \"hllo\"\n"},
+		{"history up", "\x1b[A\x1b[A\r", "print x + 1\n"},
+		{"history down keeps draft", "dra\x1b[A\x1b[Bft\r", "draft\n"},
+		{"history stops at oldest", "\x1b[A\x1b[A\x1b[A\x1b[A\x1b[A\r", "int x := 1\n"},
+		{"complete unique", "tot\t + 1\r", "total + 1\n"},
+		{"complete common prefix", "pr\tn\t 8\r", "print 8\n"},
+		{"tab indents", "\tx\r", "  x\n"},
+		{"search", "\x12x\r", "print x + 1\n"},
+		{"search older", "\x12x\x12\r", "int x := 1\n"},
+		{"search then edit", "\x12fn\x1b[C}\r", "fn f() {}\n"},
+		{"search cancelled", "keep\x12x\x07\r", "keep\n"},
+		{"search backspace", "\x12fnz\x7f\r", "fn f() {\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line, err := readLine(t, history, test.keys)
+			if err != nil {
+				t.Fatalf("reading %q: %v", test.keys, err)
+			}
+			if line != test.want {
+				t.Errorf("keys %q read %q, want %q", test.keys, line, test.want)
+			}
+		})
+	}
+}
+
+func TestLineEditorSignals(t *testing.T) {
+	if _, err := readLine(t, nil, "\x04"); err != io.EOF {
+		t.Errorf("Ctrl-D on an empty line returned %v, want EOF", err)
+	}
+	if _, err := readLine(t, nil, "print\x03"); err != ErrInterrupted {
+		t.Errorf("Ctrl-C returned %v, want ErrInterrupted", err)
+	}
+	if line, err := readLine(t, nil, "print 9\x01\x04\x04\x04\x04\x04\x04\r"); err != nil || line != "9\n" {
+		t.Errorf("Ctrl-D on a line read %q, %v, want it to delete forwards", line, err)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	editor := NewLineEditor(strings.NewReader("int y := 2\r\r  \rint y := 2\rprint y\r"), ioutil.Discard, nil)
+	editor.LoadHistory(path)
+	for idx := 0; idx < 5; idx++ {
+		if _, err := editor.ReadLine("> "); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := editor.SaveHistory(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewLineEditor(strings.NewReader(""), ioutil.Discard, nil)
+	reloaded.LoadHistory(path)
+	if got := strings.Join(reloaded.history, "|"); got != "int y := 2|print y" {
+		t.Errorf("reloaded history %q, want blank and repeated lines left out", got)
+	}
+}
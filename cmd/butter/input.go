@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+/*LineReader reads REPL input a line at a time, after showing a prompt */
+type LineReader interface {
+	ReadLine(prompt string) (string, error)
+	Close() error
+}
+
+/*NewLineReader returns a line editor when stdin is a terminal, keeping its history in ~/.butter_history, or
+  a reader of plain lines when input is piped in */
+func NewLineReader(complete func() []string) LineReader {
+	fd := int(os.Stdin.Fd())
+	if !isTerminal(fd) {
+		return &plainReader{bufio.NewReader(os.Stdin)}
+	}
+	editor := NewLineEditor(os.Stdin, os.Stdout, complete)
+	if home, err := os.UserHomeDir(); err == nil {
+		editor.LoadHistory(filepath.Join(home, ".butter_history"))
+	}
+	return &terminalReader{fd, editor}
+}
+
+/*plainReader reads lines without any editing */
+type plainReader struct {
+	reader *bufio.Reader
+}
+
+func (p *plainReader) ReadLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	return p.reader.ReadString('\n')
+}
+
+func (p *plainReader) Close() error {
+	return nil
+}
+
+/*terminalReader edits lines with a LineEditor, only keeping the terminal in raw mode while a line is being
+  edited so programs run with it as normal */
+type terminalReader struct {
+	fd     int
+	editor *LineEditor
+}
+
+func (t *terminalReader) ReadLine(prompt string) (string, error) {
+	state, err := makeRaw(t.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restoreTerminal(t.fd, state)
+	return t.editor.ReadLine(prompt)
+}
+
+/*Close saves the history for the next session */
+func (t *terminalReader) Close() error {
+	return t.editor.SaveHistory()
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/abrahampost/Butter"
+)
+
+/*Exit statuses, telling scripts which kind of error stopped the program */
+const (
+	exitOK = iota
+	exitRuntime
+	exitUsage
+	exitSyntax
+	exitType
+	exitFile
+)
+
+/*Settings struct Contains the settings for the current interpreter */
+type Settings struct {
+	//command is the subcommand to run: run, repl, check, fmt, tokens, ast, version or help
+	command  string
+	bytecode bool
+	//write makes fmt rewrite the file rather than print the result
+	write bool
+	//the program is either the source passed with -e or read from fileLoc, which is - for stdin
+	expr     string
+	fromExpr bool
+	fileLoc  string
+	//args are the arguments following the program, which are passed on to it
+	args []string
+}
+
+/*subcommands lists every subcommand along with a description for the usage message */
+var subcommands = [][2]string{
+	{"run", "run a program, the default when one is given"},
+	{"repl", "start the interactive prompt, the default when no program is given"},
+	{"check", "report syntax and type errors without running the program"},
+	{"fmt", "print the program laid out in the standard style, -w rewrites the file instead"},
+	{"tokens", "print the tokens the program is split into"},
+	{"ast", "print the syntax tree the program is parsed into"},
+	{"version", "print the version"},
+	{"help", "print this message"},
+}
+
+/*UsageError is a mistake in the command line arguments */
+type UsageError struct {
+	message string
+}
+
+func (e *UsageError) Error() string {
+	return e.message
+}
+
+func usageError(format string, args ...interface{}) error {
+	return &UsageError{fmt.Sprintf(format, args...)}
+}
+
+/*Parse the command line arguments into settings. Flags and a subcommand come first, then the program as a
+  file, - for stdin or -e <source>. Every argument after the program is passed on to it */
+func (s *Settings) Parse(args []string) error {
+	help, version := false, false
+	hasProgram := func() bool { return s.fromExpr || s.fileLoc != "" }
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		if hasProgram() {
+			s.args = append(s.args, args[idx:]...)
+			break
+		}
+		switch {
+		case arg == "--vm":
+			s.bytecode = true
+		case arg == "-w":
+			s.write = true
+		case arg == "-h" || arg == "--help":
+			help = true
+		case arg == "--version":
+			version = true
+		case arg == "-e":
+			if idx+1 == len(args) {
+				return usageError("-e expects source to run")
+			}
+			idx++
+			s.expr, s.fromExpr = args[idx], true
+		case arg == "--":
+			if idx+1 < len(args) {
+				idx++
+				s.fileLoc = args[idx]
+			}
+		case arg == "-":
+			s.fileLoc = arg
+		case strings.HasPrefix(arg, "-"):
+			return usageError("unknown flag '%s'", arg)
+		case s.command == "" && isSubcommand(arg):
+			s.command = arg
+		default:
+			s.fileLoc = arg
+		}
+	}
+	switch {
+	case help:
+		s.command = "help"
+		return nil
+	case version:
+		s.command = "version"
+		return nil
+	case s.command == "":
+		s.command = "run"
+		if !hasProgram() {
+			s.command = "repl"
+		}
+	}
+	switch s.command {
+	case "repl", "version", "help":
+		if hasProgram() {
+			return usageError("%s doesn't take a program", s.command)
+		}
+	default:
+		if !hasProgram() {
+			return usageError("%s expects a file, - or -e <source>", s.command)
+		}
+		if s.command != "run" && len(s.args) > 0 {
+			return usageError("unexpected argument '%s'", s.args[0])
+		}
+	}
+	if s.write && (s.command != "fmt" || s.fromExpr || s.fileLoc == "-") {
+		return usageError("-w only rewrites a file passed to fmt")
+	}
+	return nil
+}
+
+func isSubcommand(name string) bool {
+	for _, subcommand := range subcommands {
+		if subcommand[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+/*Source reads the program named on the command line */
+func (s *Settings) Source() (*butter.Source, error) {
+	switch {
+	case s.fromExpr:
+		return butter.NewSource("<eval>", s.expr), nil
+	case s.fileLoc == "-":
+		text, err := ioutil.ReadAll(os.Stdin)
+		return butter.NewSource("<stdin>", string(text)), err
+	default:
+		text, err := ioutil.ReadFile(s.fileLoc)
+		return butter.NewSource(s.fileLoc, string(text)), err
+	}
+}
+
+var vm *butter.Runtime
+
+func main() {
+	settings := Settings{}
+	CheckError(settings.Parse(os.Args[1:]))
+
+	vm = butter.New()
+	vm.UseBytecode(settings.bytecode)
+
+	switch settings.command {
+	case "help":
+		PrintUsage(os.Stdout)
+	case "version":
+		fmt.Printf("Butter v%s\n", butter.VERSION)
+	case "repl":
+		RunPrompt()
+	default:
+		CheckError(RunProgram(settings))
+	}
+}
+
+/*PrintUsage describes the command line */
+func PrintUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: butter [flags] [command] [file | - | -e <source>] [args...]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, subcommand := range subcommands {
+		fmt.Fprintf(w, "  %-9s %s\n", subcommand[0], subcommand[1])
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fmt.Fprintln(w, "  -e <source>  run source given on the command line instead of a file")
+	fmt.Fprintln(w, "  -            read the program from stdin")
+	fmt.Fprintln(w, "  --vm         run the program on the bytecode machine")
+	fmt.Fprintln(w, "  -w           make fmt rewrite the file")
+	fmt.Fprintln(w, "  --version    print the version")
+	fmt.Fprintln(w, "  -h, --help   print this message")
+	fmt.Fprintln(w, "\nArguments after the program are available to it as the list<string> args.")
+	fmt.Fprintln(w, "\nExit status: 0 on success, 1 for runtime errors, 2 for usage errors, 3 for syntax and scope errors,")
+	fmt.Fprintln(w, "4 for type errors and 5 if a file could not be read or written.")
+}
+
+/*RunProgram runs the subcommand given on the command line against its program */
+func RunProgram(s Settings) error {
+	source, err := s.Source()
+	if err != nil {
+		return err
+	}
+	switch s.command {
+	case "check":
+		return vm.CheckSource(source)
+	case "fmt":
+		return FormatProgram(source, s.write)
+	case "tokens":
+		return ShowTokens(source)
+	case "ast":
+		return ShowAst(source)
+	}
+	vm.SetArgs(s.args)
+	_, err = vm.EvalSource(source)
+	return err
+}
+
+/*FormatProgram prints the formatted program, or with write set saves it back to its file if it has changed */
+func FormatProgram(source *butter.Source, write bool) error {
+	formatted, err := butter.Format(source)
+	if err != nil {
+		return err
+	}
+	if !write {
+		fmt.Print(formatted)
+		return nil
+	}
+	if formatted == source.Text {
+		return nil
+	}
+	return ioutil.WriteFile(source.Name, []byte(formatted), 0644)
+}
+
+/*RunPrompt runs the REPL and feeds input to the runtime as it comes in, printing the value of each expression.
+  Errors are reported without ending the session, and the REPL exits at the end of input (Ctrl-D) */
+func RunPrompt() {
+	fmt.Printf("Butterv%s (repl)\n", butter.VERSION)
+	vm.UseREPL(true)
+	reader := NewLineReader(vm.Names)
+	defer reader.Close()
+	for true {
+		input, err := ReadInput(reader)
+		if err == ErrInterrupted {
+			continue
+		}
+		if IsCommand(input) {
+			RunCommand(input)
+		} else if input != "" {
+			Run(input)
+		}
+		if err == io.EOF {
+			fmt.Println()
+			return
+		}
+		CheckError(err)
+	}
+}
+
+/*ReadInput reads a line, then keeps reading lines behind a "..." prompt while the statements so far are
+  incomplete, like an open block or a trailing operator. Whatever was read is returned along with any read error */
+func ReadInput(reader LineReader) (string, error) {
+	input, err := reader.ReadLine("> ")
+	for err == nil && Incomplete(input) {
+		var line string
+		line, err = reader.ReadLine("... ")
+		input += line
+	}
+	return input, err
+}
+
+/*Incomplete returns true if the REPL input so far is unfinished, either as source or as a command's argument */
+func Incomplete(input string) bool {
+	if IsCommand(input) {
+		return CommandIncomplete(input)
+	}
+	return butter.Incomplete(input)
+}
+
+/*Run sends input from the REPL to the embedded runtime, reporting any error it returns. Globals defined before
+  the error are kept */
+func Run(source string) {
+	if _, err := vm.Eval(source); err != nil {
+		fmt.Fprintln(os.Stderr, butter.FormatError(err))
+	}
+}
+
+/*CheckError stops execution of the program with a panic-like error message if an error has been reported. The
+  exit status tells which kind of error it was */
+func CheckError(err error) {
+	if err == nil {
+		return
+	}
+	if _, ok := err.(*UsageError); ok {
+		fmt.Fprintf(os.Stderr, "butter: %s\nRun 'butter --help' for usage\n", err)
+	} else {
+		fmt.Fprintln(os.Stderr, butter.FormatError(err))
+	}
+	os.Exit(exitCode(err))
+}
+
+/*exitCode returns the exit status for an error */
+func exitCode(err error) int {
+	switch err.(type) {
+	case nil:
+		return exitOK
+	case *UsageError:
+		return exitUsage
+	case butter.ParseErrors, *butter.ParseError:
+		return exitSyntax
+	case butter.TypeCheckErrors, *butter.TypeCheckError:
+		return exitType
+	case *os.PathError:
+		return exitFile
+	}
+	return exitRuntime
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package main
+
+import "errors"
+
+/*terminalState is empty where raw mode isn't supported, the REPL reads plain lines instead */
+type terminalState struct{}
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (*terminalState, error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+func restoreTerminal(fd int, state *terminalState) error {
+	return nil
+}
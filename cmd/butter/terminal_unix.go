@@ -0,0 +1,51 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+/*terminalState is the mode a terminal was in before the line editor changed it */
+type terminalState struct {
+	termios syscall.Termios
+}
+
+/*isTerminal returns true if fd refers to a terminal */
+func isTerminal(fd int) bool {
+	var termios syscall.Termios
+	return ioctlTermios(fd, ioctlGetTermios, &termios) == nil
+}
+
+/*makeRaw puts a terminal into raw mode, where keys are read one at a time without being echoed or turned into
+  signals, returning the mode to restore afterwards */
+func makeRaw(fd int) (*terminalState, error) {
+	var state terminalState
+	if err := ioctlTermios(fd, ioctlGetTermios, &state.termios); err != nil {
+		return nil, err
+	}
+	raw := state.termios
+	raw.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctlTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+/*restoreTerminal puts a terminal back into the mode it was in before makeRaw */
+func restoreTerminal(fd int, state *terminalState) error {
+	return ioctlTermios(fd, ioctlSetTermios, &state.termios)
+}
+
+func ioctlTermios(fd int, request uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
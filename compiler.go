@@ -0,0 +1,727 @@
+package butter
+
+import "fmt"
+
+/*CompiledFunction is a function, or the top level of a program, which has been compiled to bytecode */
+type CompiledFunction struct {
+	declaration  FuncDeclaration
+	name         string
+	arity        int
+	upvalueCount int
+	chunk        *Chunk
+}
+
+/*Type returns a string representation of the compiled function's type */
+func (f *CompiledFunction) Type() string {
+	return string(FUNCOBJ)
+}
+
+/*local is a variable stored in a slot of the running function's stack window */
+type local struct {
+	name     string
+	depth    int
+	captured bool
+	//declared is false for a slot reserved at the start of a scope until its declaration has run
+	declared bool
+}
+
+/*upvalueRef tells a closure where to find a variable of an enclosing function, either in a slot of the
+  function directly enclosing it or in one of that function's own upvalues */
+type upvalueRef struct {
+	index   int
+	isLocal bool
+}
+
+/*loopContext is what break and continue statements need to know about the loop they apply to */
+type loopContext struct {
+	label string
+	//locals and tries are the number of locals and try handlers to unwind back to when leaving an iteration
+	locals int
+	tries  int
+	//start is where continue jumps back to, or -1 if continue jumps forward to the increment
+	start     int
+	breaks    []int
+	continues []int
+}
+
+/*tryContext is a try handler which is active at the point being compiled. Leaving it early with return, break
+  or continue must remove the handler and run its finally block, which is nil for catch handlers */
+type tryContext struct {
+	locals  int
+	finally []Stmt
+}
+
+/*conditionMessages are the errors raised by a jump whose condition is not a boolean, by the kind of statement */
+var conditionMessages = []string{
+	"Cannot use non boolean value in if conditional",
+	"Cannot use non boolean value in while condition",
+	"Cannot use non boolean value in for condition",
+}
+
+const (
+	conditionIf = iota
+	conditionWhile
+	conditionFor
+)
+
+/*Compiler turns parsed statements into bytecode for the Machine, resolving each local variable to the stack
+  slot it will live in. Variables declared at the top level of a program are globals, stored by name */
+type Compiler struct {
+	enclosing  *Compiler
+	function   *CompiledFunction
+	locals     []local
+	upvalues   []upvalueRef
+	scopeDepth int
+	loops      []*loopContext
+	tries      []tryContext
+	pos        Position
+}
+
+/*NewCompiler returns a compiler for the top level of a program */
+func NewCompiler() *Compiler {
+	function := &CompiledFunction{name: "<main>", chunk: &Chunk{}}
+	//slot zero holds the function being run
+	return &Compiler{function: function, locals: []local{{declared: true}}}
+}
+
+/*Compile compiles a program into a function which returns the value of its final statement if it is an
+  expression. Programs too large to address are reported as ParseErrors */
+func Compile(stmts []Stmt, repl bool) (function *CompiledFunction, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			parseErr, ok := r.(*ParseError)
+			if !ok {
+				panic(r)
+			}
+			err = ParseErrors{parseErr}
+		}
+	}()
+	c := NewCompiler()
+	for idx, stmt := range stmts {
+		exprStmt, ok := stmt.(ExprStmt)
+		if !ok {
+			c.compileStmt(stmt)
+			continue
+		}
+		c.compileExpr(exprStmt.expr)
+		if repl {
+			c.emitOp(OpEcho)
+		}
+		if idx == len(stmts)-1 {
+			c.emitOp(OpReturn)
+			return c.function, nil
+		}
+		c.emitOp(OpPop)
+	}
+	c.emitOp(OpNil)
+	c.emitOp(OpReturn)
+	return c.function, nil
+}
+
+func (c *Compiler) chunk() *Chunk {
+	return c.function.chunk
+}
+
+/*emitOp writes an instruction and its operands, which are encoded according to operandWidths */
+func (c *Compiler) emitOp(op Opcode, operands ...int) {
+	c.chunk().write(byte(op), c.pos)
+	for idx, width := range operandWidths[op][:len(operands)] {
+		if width == 2 {
+			c.emitShort(operands[idx])
+		} else {
+			c.chunk().write(byte(operands[idx]), c.pos)
+		}
+	}
+}
+
+func (c *Compiler) emitShort(value int) {
+	if value > 0xFFFF {
+		parseError(c.pos, "Too much code or too many constants in one function")
+	}
+	c.chunk().write(byte(value>>8), c.pos)
+	c.chunk().write(byte(value), c.pos)
+}
+
+/*emitJump writes a jump whose offset is filled in later by patchJump, returning where the offset is */
+func (c *Compiler) emitJump(op Opcode, operands ...int) int {
+	c.emitOp(op, operands...)
+	c.emitShort(0xFFFF)
+	return len(c.chunk().code) - 2
+}
+
+/*patchJump points a forward jump at the next instruction to be written */
+func (c *Compiler) patchJump(offset int) {
+	jump := len(c.chunk().code) - offset - 2
+	if jump > 0xFFFF {
+		parseError(c.pos, "Too much code to jump over")
+	}
+	c.chunk().code[offset] = byte(jump >> 8)
+	c.chunk().code[offset+1] = byte(jump)
+}
+
+/*emitLoop writes a backward jump to start */
+func (c *Compiler) emitLoop(start int) {
+	c.emitOp(OpLoop)
+	c.emitShort(len(c.chunk().code) - start + 2)
+}
+
+func (c *Compiler) constant(value Object) int {
+	return c.chunk().addConstant(value)
+}
+
+func (c *Compiler) name(token Token) int {
+	return c.constant(String{token.literal})
+}
+
+/*typeOperand returns the operand describing a declared type */
+func (c *Compiler) typeOperand(varType TypeSpec) int {
+	if varType.Inferred() {
+		return typeInferred
+	}
+	return c.chunk().addType(varType)
+}
+
+func (c *Compiler) isGlobal() bool {
+	return c.enclosing == nil && c.scopeDepth == 0
+}
+
+func (c *Compiler) beginScope() {
+	c.scopeDepth++
+}
+
+/*endScope discards the locals declared in the scope, closing any which were captured by a closure */
+func (c *Compiler) endScope() {
+	c.scopeDepth--
+	count := len(c.locals)
+	for count > 0 && c.locals[count-1].depth > c.scopeDepth {
+		count--
+	}
+	c.popLocals(count)
+}
+
+/*popLocals emits instructions discarding locals until only count remain */
+func (c *Compiler) popLocals(count int) {
+	for len(c.locals) > count {
+		if c.locals[len(c.locals)-1].captured {
+			c.emitOp(OpCloseUpvalue)
+		} else {
+			c.emitOp(OpPop)
+		}
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+/*addLocal adds a local which is already on the stack, such as a parameter or loop variable */
+func (c *Compiler) addLocal(name string) {
+	c.locals = append(c.locals, local{name, c.scopeDepth, false, true})
+}
+
+/*reserve pushes a placeholder for every variable the statements declare, giving each one its slot before any
+  of them run. A function can then refer to a variable declared after it, like the resolver allows */
+func (c *Compiler) reserve(stmts []Stmt) {
+	for _, name := range declaredNames(stmts) {
+		c.emitOp(OpConstant, c.constant(&undeclared{name.literal}))
+		c.locals = append(c.locals, local{name.literal, c.scopeDepth, false, false})
+	}
+}
+
+/*declareLocal marks the slot reserved for a variable as declared, returning the slot */
+func (c *Compiler) declareLocal(name Token) int {
+	for idx := len(c.locals) - 1; idx >= 0 && c.locals[idx].depth == c.scopeDepth; idx-- {
+		if c.locals[idx].name == name.literal && !c.locals[idx].declared {
+			c.locals[idx].declared = true
+			return idx
+		}
+	}
+	parseError(name.pos, "No slot reserved for '"+name.literal+"'")
+	return -1
+}
+
+/*resolveLocal finds the slot of a local variable. Variables whose declarations haven't run yet are only found
+  from within the functions declared before them */
+func (c *Compiler) resolveLocal(name string, forward bool) int {
+	for idx := len(c.locals) - 1; idx >= 0; idx-- {
+		if c.locals[idx].name == name && (c.locals[idx].declared || forward) {
+			return idx
+		}
+	}
+	return -1
+}
+
+/*resolveUpvalue finds a variable in an enclosing function, threading it through the upvalues of every
+  function in between */
+func (c *Compiler) resolveUpvalue(name string) int {
+	if c.enclosing == nil {
+		return -1
+	}
+	if slot := c.enclosing.resolveLocal(name, true); slot != -1 {
+		c.enclosing.locals[slot].captured = true
+		return c.addUpvalue(slot, true)
+	}
+	if index := c.enclosing.resolveUpvalue(name); index != -1 {
+		return c.addUpvalue(index, false)
+	}
+	return -1
+}
+
+func (c *Compiler) addUpvalue(index int, isLocal bool) int {
+	for idx, upvalue := range c.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return idx
+		}
+	}
+	c.upvalues = append(c.upvalues, upvalueRef{index, isLocal})
+	c.function.upvalueCount = len(c.upvalues)
+	return len(c.upvalues) - 1
+}
+
+/*declareVariable stores the value on top of the stack as a new variable with the passed type operand */
+func (c *Compiler) declareVariable(name Token, typeOperand int) {
+	if c.isGlobal() {
+		c.emitOp(OpDefineGlobal, c.name(name), typeOperand)
+		return
+	}
+	c.emitOp(OpDeclareLocal, c.name(name), typeOperand, c.declareLocal(name))
+}
+
+/*getVariable loads a variable from a local slot, an upvalue or the globals */
+func (c *Compiler) getVariable(name Token) {
+	if slot := c.resolveLocal(name.literal, false); slot != -1 {
+		c.emitOp(OpGetLocal, slot)
+	} else if index := c.resolveUpvalue(name.literal); index != -1 {
+		c.emitOp(OpGetUpvalue, index)
+	} else {
+		c.emitOp(OpGetGlobal, c.name(name))
+	}
+}
+
+/*setVariable assigns the value on top of the stack to a variable, leaving nil in its place */
+func (c *Compiler) setVariable(name Token) {
+	if slot := c.resolveLocal(name.literal, false); slot != -1 {
+		c.emitOp(OpSetLocal, slot)
+	} else if index := c.resolveUpvalue(name.literal); index != -1 {
+		c.emitOp(OpSetUpvalue, index)
+	} else {
+		c.emitOp(OpSetGlobal, c.name(name))
+	}
+}
+
+func (c *Compiler) compileStmts(stmts []Stmt) {
+	for _, stmt := range stmts {
+		c.compileStmt(stmt)
+	}
+}
+
+/*compileScoped compiles statements in a new scope, as the interpreter runs them in a new environment */
+func (c *Compiler) compileScoped(stmts []Stmt) {
+	c.beginScope()
+	c.reserve(stmts)
+	c.compileStmts(stmts)
+	c.endScope()
+}
+
+/*compileBody compiles the body of an if statement or loop, giving it a scope of its own even if it isn't a block */
+func (c *Compiler) compileBody(body Stmt) {
+	if block, ok := body.(Block); ok {
+		c.compileStmt(block)
+		return
+	}
+	c.compileScoped([]Stmt{body})
+}
+
+func (c *Compiler) compileStmt(stmt Stmt) {
+	prevPos := c.pos
+	c.pos = stmt.Position()
+	defer func() { c.pos = prevPos }()
+	switch s := stmt.(type) {
+	case Print:
+		c.compileExpr(s.expr)
+		c.emitOp(OpPrint)
+	case ExprStmt:
+		c.compileExpr(s.expr)
+		c.emitOp(OpPop)
+	case VarDeclaration:
+		c.compileVarDeclaration(s)
+	case If:
+		c.compileExpr(s.condition)
+		elseJump := c.emitJump(OpJumpIfFalse, conditionIf)
+		c.compileBody(s.ifTrue)
+		if s.ifFalse == nil {
+			c.patchJump(elseJump)
+			return
+		}
+		endJump := c.emitJump(OpJump)
+		c.patchJump(elseJump)
+		c.compileBody(s.ifFalse)
+		c.patchJump(endJump)
+	case While:
+		start := len(c.chunk().code)
+		c.compileExpr(s.condition)
+		exit := c.emitJump(OpJumpIfFalse, conditionWhile)
+		loop := c.beginLoop(s.label, start)
+		c.compileBody(s.body)
+		c.emitLoop(start)
+		c.patchJump(exit)
+		c.endLoop(loop)
+	case For:
+		c.compileFor(s)
+	case ForIn:
+		c.compileForIn(s)
+	case LoopControl:
+		c.compileLoopControl(s)
+	case Block:
+		c.compileScoped(s.stmts)
+	case FuncDeclaration:
+		c.compileFuncDeclaration(s)
+	case StructDeclaration:
+		c.emitOp(OpStruct, c.constant(&Struct{declaration: s}))
+		c.declareVariable(s.name, typeUntyped)
+	case Return:
+		if s.value != nil {
+			c.compileExpr(s.value)
+		} else {
+			c.emitOp(OpNil)
+		}
+		c.leaveTries()
+		c.emitOp(OpReturn)
+	case Try:
+		c.compileTry(s)
+	case Throw:
+		c.compileExpr(s.value)
+		c.emitOp(OpThrow)
+	case ErrorStmt:
+		c.emitOp(OpConstant, c.constant(String{s.message}))
+		c.emitOp(OpPrint)
+	}
+}
+
+/*compileVarDeclaration compiles a declaration's initializer, or its type's zero value, into a new variable */
+func (c *Compiler) compileVarDeclaration(vd VarDeclaration) {
+	if vd.initializer != nil {
+		c.compileExpr(vd.initializer)
+	} else {
+		//lists and maps are built fresh every time, the declaration pins their types
+		switch vd.varType.token.Type {
+		case LISTTYPE:
+			c.emitOp(OpList, 0)
+		case MAPTYPE:
+			c.emitOp(OpMap, 0)
+		default:
+			c.emitOp(OpConstant, c.constant(ZeroValue(vd.varType)))
+		}
+	}
+	c.declareVariable(vd.identifier, c.typeOperand(vd.varType))
+}
+
+func (c *Compiler) beginLoop(label string, start int) *loopContext {
+	loop := &loopContext{label: label, locals: len(c.locals), tries: len(c.tries), start: start}
+	c.loops = append(c.loops, loop)
+	return loop
+}
+
+/*endLoop points the loop's break statements at the next instruction */
+func (c *Compiler) endLoop(loop *loopContext) {
+	for _, jump := range loop.breaks {
+		c.patchJump(jump)
+	}
+	c.loops = c.loops[:len(c.loops)-1]
+}
+
+func (c *Compiler) compileFor(f For) {
+	c.beginScope()
+	if f.initializer != nil {
+		c.reserve([]Stmt{f.initializer})
+		c.compileStmt(f.initializer)
+	}
+	start := len(c.chunk().code)
+	exit := -1
+	if f.condition != nil {
+		c.compileExpr(f.condition)
+		exit = c.emitJump(OpJumpIfFalse, conditionFor)
+	}
+	loop := c.beginLoop(f.label, -1)
+	c.compileBody(f.body)
+	for _, jump := range loop.continues {
+		c.patchJump(jump)
+	}
+	if f.increment != nil {
+		c.compileExpr(f.increment)
+		c.emitOp(OpPop)
+	}
+	c.emitLoop(start)
+	if exit != -1 {
+		c.patchJump(exit)
+	}
+	c.endLoop(loop)
+	c.endScope()
+}
+
+/*compileForIn iterates over a snapshot of the collection held in a hidden local. Each iteration declares
+  the loop variables in a scope of their own, so closures capture the values from that iteration */
+func (c *Compiler) compileForIn(f ForIn) {
+	c.beginScope()
+	c.compileExpr(f.iterable)
+	c.emitOp(OpIterStart, len(f.names))
+	c.locals = append(c.locals, local{depth: c.scopeDepth})
+	iterator := len(c.locals) - 1
+	start := len(c.chunk().code)
+	exit := c.emitJump(OpIterNext, iterator, len(f.names))
+	loop := c.beginLoop(f.label, start)
+	c.beginScope()
+	for _, name := range f.names {
+		c.addLocal(name.literal)
+	}
+	c.compileBody(f.body)
+	c.endScope()
+	c.emitLoop(start)
+	c.patchJump(exit)
+	c.endLoop(loop)
+	c.endScope()
+}
+
+/*compileLoopControl leaves the scopes and try blocks within the loop, then jumps out of it or back to its start */
+func (c *Compiler) compileLoopControl(l LoopControl) {
+	loop := c.loops[len(c.loops)-1]
+	if l.label != "" {
+		for idx := len(c.loops) - 1; idx >= 0; idx-- {
+			if c.loops[idx].label == l.label {
+				loop = c.loops[idx]
+				break
+			}
+		}
+	}
+	c.unwind(loop.locals, loop.tries)
+	if l.keyword.Type == BREAK {
+		loop.breaks = append(loop.breaks, c.emitJump(OpJump))
+	} else if loop.start == -1 {
+		loop.continues = append(loop.continues, c.emitJump(OpJump))
+	} else {
+		c.emitLoop(loop.start)
+	}
+}
+
+/*unwind emits the instructions which leave every scope and try handler entered since the passed counts,
+  running finally blocks on the way out. The compiler's own state is left as it was */
+func (c *Compiler) unwind(locals int, tries int) {
+	savedLocals := append([]local(nil), c.locals...)
+	savedTries := append([]tryContext(nil), c.tries...)
+	for idx := len(c.tries) - 1; idx >= tries; idx-- {
+		handler := c.tries[idx]
+		c.popLocals(handler.locals)
+		c.emitOp(OpEndTry)
+		c.tries = c.tries[:idx]
+		if handler.finally != nil {
+			c.compileScoped(handler.finally)
+		}
+	}
+	c.popLocals(locals)
+	c.locals, c.tries = savedLocals, savedTries
+}
+
+/*leaveTries removes every try handler of the current function before a return, running finally blocks above
+  the value being returned */
+func (c *Compiler) leaveTries() {
+	if len(c.tries) == 0 {
+		return
+	}
+	savedLocals := append([]local(nil), c.locals...)
+	savedTries := append([]tryContext(nil), c.tries...)
+	c.locals = append(c.locals, local{depth: c.scopeDepth})
+	for idx := len(c.tries) - 1; idx >= 0; idx-- {
+		handler := c.tries[idx]
+		c.emitOp(OpEndTry)
+		c.tries = c.tries[:idx]
+		if handler.finally != nil {
+			c.compileScoped(handler.finally)
+		}
+	}
+	c.locals, c.tries = savedLocals, savedTries
+}
+
+/*compileTry installs a handler for the finally block around a handler for the catch block. A caught error
+  is pushed onto the stack as the catch variable, and an error caught for the finally block is thrown again
+  once the block has run */
+func (c *Compiler) compileTry(t Try) {
+	var finallyHandler, catchHandler int
+	if t.finally != nil {
+		finallyHandler = c.emitJump(OpTry)
+		c.tries = append(c.tries, tryContext{len(c.locals), t.finally})
+	}
+	if t.catchName != nil {
+		catchHandler = c.emitJump(OpTry)
+		c.tries = append(c.tries, tryContext{len(c.locals), nil})
+	}
+	c.compileScoped(t.body)
+	if t.catchName != nil {
+		c.emitOp(OpEndTry)
+		c.tries = c.tries[:len(c.tries)-1]
+		skip := c.emitJump(OpJump)
+		c.patchJump(catchHandler)
+		c.beginScope()
+		//the handler leaves the caught error in the catch variable's slot
+		c.addLocal(t.catchName.literal)
+		c.reserve(t.catchBody)
+		c.compileStmts(t.catchBody)
+		c.endScope()
+		c.patchJump(skip)
+	}
+	if t.finally != nil {
+		c.emitOp(OpEndTry)
+		c.tries = c.tries[:len(c.tries)-1]
+		c.compileScoped(t.finally)
+		skip := c.emitJump(OpJump)
+		c.patchJump(finallyHandler)
+		c.beginScope()
+		c.locals = append(c.locals, local{depth: c.scopeDepth})
+		c.compileScoped(t.finally)
+		c.emitOp(OpThrow)
+		//the error was consumed by the throw, so the scope is left without popping it
+		c.locals = c.locals[:len(c.locals)-1]
+		c.scopeDepth--
+		c.patchJump(skip)
+	}
+}
+
+/*compileFuncDeclaration creates a closure and stores it as a variable, or attaches it to its receiver's
+  struct if it is a method */
+func (c *Compiler) compileFuncDeclaration(fd FuncDeclaration) {
+	if fd.receiver != nil {
+		c.getVariable(fd.receiver.varType.token)
+		c.compileFunction(fd)
+		c.emitOp(OpMethod, c.name(fd.name))
+		return
+	}
+	c.compileFunction(fd)
+	c.declareVariable(fd.name, typeUntyped)
+}
+
+/*compileFunction compiles a function's body with its own compiler, then emits the instruction which
+  creates a closure over the variables it captures */
+func (c *Compiler) compileFunction(fd FuncDeclaration) {
+	function := &CompiledFunction{declaration: fd, name: functionName(fd), arity: len(fd.params), chunk: &Chunk{}}
+	sub := &Compiler{enclosing: c, function: function, scopeDepth: 1, pos: fd.Position()}
+	//slot zero holds the receiver of a method, or the function itself. It sits outside the body's scope, as
+	//the interpreter binds the receiver in an environment of its own
+	receiver := local{declared: true}
+	if fd.receiver != nil {
+		receiver.name = fd.receiver.name.literal
+	}
+	sub.locals = append(sub.locals, receiver)
+	for _, param := range fd.params {
+		sub.addLocal(param.name.literal)
+	}
+	sub.reserve(fd.body)
+	sub.compileStmts(fd.body)
+	sub.emitOp(OpNil)
+	sub.emitOp(OpReturn)
+	c.emitOp(OpClosure, c.constant(function))
+	for _, upvalue := range sub.upvalues {
+		isLocal := 0
+		if upvalue.isLocal {
+			isLocal = 1
+		}
+		c.chunk().write(byte(isLocal), c.pos)
+		c.emitShort(upvalue.index)
+	}
+}
+
+func (c *Compiler) compileExpr(e Expr) {
+	prevPos := c.pos
+	c.pos = e.Position()
+	defer func() { c.pos = prevPos }()
+	switch e := e.(type) {
+	case Literal:
+		switch value := e.obj.(type) {
+		case Boolean:
+			if value.Value {
+				c.emitOp(OpTrue)
+			} else {
+				c.emitOp(OpFalse)
+			}
+		case Nil:
+			c.emitOp(OpNil)
+		default:
+			c.emitOp(OpConstant, c.constant(value))
+		}
+	case Variable:
+		c.getVariable(e.identifier)
+	case Assign:
+		c.compileExpr(e.initializer)
+		c.setVariable(e.identifier)
+	case Grouping:
+		c.compileExpr(e.expr)
+	case Binary:
+		c.compileExpr(e.left)
+		c.compileExpr(e.right)
+		c.emitOp(OpBinary, int(e.operator.Type))
+	case Logical:
+		c.compileExpr(e.left)
+		end := c.emitJump(OpLogical, int(e.operator.Type))
+		c.compileExpr(e.right)
+		c.emitOp(OpCheckBool, int(e.operator.Type))
+		c.patchJump(end)
+	case Unary:
+		c.compileExpr(e.right)
+		c.emitOp(OpUnary, int(e.operator.Type))
+	case Call:
+		c.compileExpr(e.callee)
+		if len(e.args) > 255 {
+			parseError(e.paren.pos, "Cannot pass more than 255 arguments")
+		}
+		for _, arg := range e.args {
+			c.compileExpr(arg)
+		}
+		c.emitOp(OpCall, len(e.args))
+	case ListLiteral:
+		for _, element := range e.elements {
+			c.compileExpr(element)
+		}
+		c.emitOp(OpList, len(e.elements))
+	case MapLiteral:
+		for idx := range e.keys {
+			c.compileExpr(e.keys[idx])
+			c.compileExpr(e.values[idx])
+		}
+		c.emitOp(OpMap, len(e.keys))
+	case Index:
+		c.compileExpr(e.object)
+		c.compileExpr(e.index)
+		c.emitOp(OpGetIndex)
+	case SetIndex:
+		c.compileExpr(e.object)
+		c.compileExpr(e.index)
+		c.compileExpr(e.value)
+		c.emitOp(OpSetIndex)
+	case Slice:
+		c.compileExpr(e.object)
+		flags := 0
+		if e.start != nil {
+			c.compileExpr(e.start)
+			flags |= 1
+		}
+		if e.end != nil {
+			c.compileExpr(e.end)
+			flags |= 2
+		}
+		c.emitOp(OpSlice, flags)
+	case Get:
+		c.compileExpr(e.object)
+		c.emitOp(OpGetProperty, c.name(e.name))
+	case Set:
+		c.compileExpr(e.object)
+		c.compileExpr(e.value)
+		c.emitOp(OpSetProperty, c.name(e.name))
+	case Interpolation:
+		for idx, part := range e.parts {
+			c.compileExpr(part)
+			if e.specs[idx] == "" {
+				c.emitOp(OpStringify)
+			} else {
+				c.emitOp(OpFormat, c.constant(String{e.specs[idx]}))
+			}
+		}
+		c.emitOp(OpConcat, len(e.parts))
+	default:
+		parseError(e.Position(), fmt.Sprintf("Cannot compile %T", e))
+	}
+}
@@ -0,0 +1,576 @@
+package butter
+
+import (
+	"bytes"
+	"testing"
+)
+
+/*conformanceTests are programs which must behave the same on the interpreter and the bytecode machine. Each
+  one lists what it prints and the runtime error which stops it, if any */
+var conformanceTests = []struct {
+	name   string
+	source string
+	output string
+	err    string
+}{
+	{"arithmetic", `
+print 1 + 2 * 3
+print 7 / 2
+print 7.0 / 2
+print 2 ** 10
+print 10 % 3
+print -(4 - 6)
+print "a" + 1
+`, "7\n3\n3.5\n1024\n1\n2\na1\n", ""},
+	{"logic", `
+bool t := true
+print t and false or t
+print !t
+print 1 < 2 == t
+`, "TRUE\nFALSE\nTRUE\n", ""},
+	{"short circuit", `
+fn loud(string s, bool b) bool {
+  print s
+  return b
+}
+int x := 0
+print x != 0 and 10 / x > 1
+print x == 0 or 10 / x > 1
+print loud("a", false) and loud("b", true)
+print loud("c", true) or loud("d", true)
+print loud("e", true) and loud("f", false) or loud("g", true)
+list<int> xs := []
+print len(xs) > 0 and xs[0] == 1
+`, "FALSE\nTRUE\na\nFALSE\nc\nTRUE\ne\nf\ng\nTRUE\nFALSE\n", ""},
+	{"variables and scopes", `
+int x := 1
+{
+  int x := 2
+  print x
+  x := 3
+  print x
+}
+print x
+var y := 2.5
+y := y * 2
+print y
+`, "2\n3\n1\n5.0\n", ""},
+	{"inferred declarations", `
+var a := 1
+let b := "s"
+var c := [1.5]
+var d := {"k": [true]}
+var e := a + 2.0
+fn half(int n) float {
+  return n / 2.0
+}
+var h := half(3)
+h := h * 2
+print "${a} ${b} ${c} ${d} ${e} ${h}"
+`, "1 s [1.5] {\"k\": [TRUE]} 3.0 3.0\n", ""},
+	{"if and while", `
+int n := 0
+while n < 5 {
+  n := n + 1
+  if n % 2 == 0 {
+    print n
+  } else if n == 5 {
+    print "five"
+  }
+}
+`, "2\n4\nfive\n", ""},
+	{"for loops", `
+int total := 0
+for int i := 0; i < 10; i := i + 1 {
+  if i == 3 {
+    continue
+  }
+  if i == 6 {
+    break
+  }
+  total := total + i
+}
+print total
+`, "12\n", ""},
+	{"labeled loops", `
+outer: for int i := 0; i < 3; i := i + 1 {
+  for int j := 0; j < 3; j := j + 1 {
+    if j == 2 {
+      continue outer
+    }
+    if i == 2 {
+      break outer
+    }
+    print "${i}${j}"
+  }
+}
+`, "00\n01\n10\n11\n", ""},
+	{"leaving loops through nested blocks", `
+int i := 100
+int n := 0
+for ; ; {
+  int i := n
+  {
+    int n := 50
+    if i == 3 {
+      break
+    }
+  }
+  n := n + 1
+}
+print i
+print n
+found: while true {
+  for x in [1, 2, 3] {
+    {
+      string x := "shadow"
+    }
+    if x == 2 {
+      print "found ${x}"
+      break found
+    }
+  }
+}
+int k := 0
+while k < 5 {
+  k := k + 1
+  {
+    if k % 2 == 1 {
+      continue
+    }
+  }
+  print k
+}
+`, "100\n3\nfound 2\n2\n4\n", ""},
+	{"for in", `
+list<string> names := ["a", "b"]
+for name in names {
+  print name
+}
+for i, name in names {
+  print "${i}=${name}"
+}
+map<string, int> ages := {"x": 1, "y": 2}
+for k, v in ages {
+  print "${k}:${v}"
+}
+`, "a\nb\n0=a\n1=b\nx:1\ny:2\n", ""},
+	{"functions and recursion", `
+fn fib(int n) int {
+  if n < 2 {
+    return n
+  }
+  return fib(n - 1) + fib(n - 2)
+}
+fn greet(string name) {
+  print "hi " + name
+}
+print fib(20)
+greet("bob")
+print greet("x")
+print fib
+`, "6765\nhi bob\nhi x\n(nil)\n<fn fib>\n", ""},
+	{"closures", `
+fn counter() {
+  int c := 0
+  fn inc() int {
+    c := c + 1
+    return c
+  }
+  return inc
+}
+var a := counter()
+var b := counter()
+a()
+print a()
+print b()
+`, "2\n1\n", ""},
+	{"closures see later assignments", `
+{
+  int x := 1
+  fn get() int {
+    return x
+  }
+  x := 5
+  print get()
+}
+`, "5\n", ""},
+	{"loop variables are captured per iteration", `
+list<int> xs := [1, 2, 3]
+int total := 0
+for x in xs {
+  fn add() {
+    total := total + x
+  }
+  add()
+}
+print total
+`, "6\n", ""},
+	{"lists", `
+list<int> xs := [1, 2, 3, 4]
+xs[0] := 10
+print xs[-1]
+print xs[1:3]
+print xs[:-2]
+append(xs, 5)
+print len(xs)
+print 3 in xs
+print xs
+`, "4\n[2, 3]\n[10, 2]\n5\nTRUE\n[10, 2, 3, 4, 5]\n", ""},
+	{"nested and shared lists", `
+list<list<int>> m := [[1], [2, 3]]
+m[1][0] := 9
+print m
+print len(m[1])
+list<int> b := m[0]
+b[0] := 5
+print m[0]
+print m[1][-2:]
+print m[:]
+list<float> fs
+print fs
+print len("héllo")
+`, "[[1], [9, 3]]\n2\n[5]\n[9, 3]\n[[5], [9, 3]]\n[]\n5\n", ""},
+	{"negative index out of range", `
+list<int> xs := [1, 2, 3]
+print xs[-3]
+print xs[-4]
+`, "1\n", "RUNTIME_ERROR [test:4:9]: Index -4 out of range for list of length 3"},
+	{"slice bound out of range", `
+list<int> xs := [1, 2, 3]
+print xs[3:]
+print xs[-5:]
+`, "[]\n", "RUNTIME_ERROR [test:4:9]: Slice bound -5 out of range for list of length 3"},
+	{"slice bounds out of order", `
+list<int> xs := [1, 2, 3]
+print xs[2:1]
+`, "", "RUNTIME_ERROR [test:3:9]: Slice bounds [2:1] are out of order"},
+	{"assignment out of range", `
+list<int> xs := [1]
+xs[1] := 2
+`, "", "RUNTIME_ERROR [test:3:3]: Index 1 out of range for list of length 1"},
+	{"maps", `
+map<string, int> m := {"a": 1}
+m["b"] := 2
+print m
+print "a" in m
+delete(m, "a")
+print keys(m)
+print values(m)
+`, "{\"a\": 1, \"b\": 2}\nTRUE\n[\"b\"]\n[2]\n", ""},
+	{"map order and key types", `
+map<string, int> m := {"b": 2, "a": 1}
+m["c"] := 3
+m["b"] := 20
+for k, v in m {
+  print "${k}=${v}"
+}
+delete(m, "b")
+m["b"] := 4
+print keys(m)
+delete(m, "zz")
+print len(m)
+map<float, bool> f := {1.5: true}
+print f[1.5]
+map<bool, string> b := {true: "y", false: "n"}
+print b[1 > 2]
+map<int, int> i := {1: 1}
+print 1 in i
+print 2 in i
+map<string, list<int>> empty
+empty["x"] := [1]
+print empty
+`, "b=20\na=1\nc=3\n[\"a\", \"c\", \"b\"]\n3\nTRUE\nn\nTRUE\nFALSE\n{\"x\": [1]}\n", ""},
+	{"structs and methods", `
+struct Point { float x; float y }
+fn (Point p) norm() float {
+  return p.x * p.x + p.y * p.y
+}
+fn (Point p) scale(float k) {
+  p.x := p.x * k
+  p.y := p.y * k
+}
+Point p := Point(3.0, 4.0)
+print p.norm()
+p.scale(2.0)
+print p
+print p.norm
+`, "25.0\nPoint{x: 6.0, y: 8.0}\n<fn Point.norm>\n", ""},
+	{"nested structs", `
+struct V {int x; int y}
+struct Seg {V a; V b}
+fn (V v) add(V o) V {
+  return V(v.x + o.x, v.y + o.y)
+}
+fn (Seg s) sum() V {
+  return s.a.add(s.b)
+}
+fn make() W {
+  return W(5)
+}
+struct W {int w}
+Seg s := Seg(V(1, 2), V(3, 4))
+print s.sum()
+V c := s.a
+c.x := 10
+print s
+s.b.y := 0
+print s.b
+print make().w
+`, "V{x: 4, y: 6}\nSeg{a: V{x: 10, y: 2}, b: V{x: 3, y: 4}}\nV{x: 3, y: 0}\n5\n", ""},
+	{"interpolation", `
+string name := "Ann"
+float price := 3.14159
+print "${name} pays ${price:.2f} for ${2 + 1:03d}"
+`, "Ann pays 3.14 for 003\n", ""},
+	{"interpolation edge cases", `
+int x := 255
+print "${"}"} ${ {"a": 1}["a"] } ${[1, 2, 3][1:2]} ${x == 255}"
+print "\${x} $ $x a$"
+print "[${3.14159:8.3f}] [${x:x}] [${x:#o}] [${"s":q}] [${x:-6d}] [${2.5:e}] [${1:}]"
+print """${x}
+${x + 1}"""
+`, "} 1 [2] TRUE\n${x} $ $x a$\n[   3.142] [ff] [0377] [\"s\"] [255   ] [2.500000e+00] [1]\n255\n256\n", ""},
+	{"format specifier for the wrong type", `
+print "${"a":d}"
+`, "", "RUNTIME_ERROR [test:2:7]: TypeError -> format 'd' cannot be used with a value of type 'String'"},
+	{"try catch finally", `
+fn div(int a, int b) int {
+  if b == 0 {
+    throw "cannot divide by zero"
+  }
+  return a / b
+}
+try {
+  print div(4, 2)
+  print div(1, 0)
+  print "unreachable"
+} catch (e) {
+  print e.kind + ": " + e.message + " at " + e.line
+} finally {
+  print "finally"
+}
+`, "2\nError: cannot divide by zero at 4\nfinally\n", ""},
+	{"runtime errors are caught", `
+try {
+  list<int> xs := [1]
+  print xs[3]
+} catch (e) {
+  print e.kind
+  print e
+}
+`, "IndexError\nIndexError: Index 3 out of range for list of length 1\n", ""},
+	{"finally runs when leaving early", `
+fn f() int {
+  for x in [1, 2, 3] {
+    try {
+      if x == 2 {
+        return x * 10
+      }
+    } finally {
+      print "leaving ${x}"
+    }
+  }
+  return 0
+}
+print f()
+while true {
+  try {
+    break
+  } finally {
+    print "broke"
+  }
+}
+`, "leaving 1\nleaving 2\n20\nbroke\n", ""},
+	{"rethrown errors keep their position", `
+try {
+  try {
+    print 1 / 0
+  } catch (e) {
+    throw e
+  }
+} catch (outer) {
+  print outer.message + " " + outer.line + ":" + outer.column
+}
+`, "Divide by zero error 4:13\n", ""},
+	{"uncaught errors run finally blocks", `
+try {
+  throw "boom"
+} finally {
+  print "cleanup"
+}
+`, "cleanup\n", "RUNTIME_ERROR [test:3:3]: boom"},
+	{"error properties", `
+try {
+  throw 42
+} catch (e) {
+  print "${e.kind} ${e.file}:${e.line}:${e.column} ${e.message}"
+}
+try {
+  print {"a": 1}["b"]
+} catch (e) {
+  print "${e.kind} ${e.file}:${e.line}:${e.column} ${e.message}"
+}
+try {
+  print 1 % 0
+} catch (e) {
+  print "${e.kind} ${e.file}:${e.line}:${e.column} ${e.message}"
+}
+`, "Error test:3:3 42\nKeyError test:8:17 Key \"b\" not found in map\nRuntimeError test:13:11 Module by zero error\n", ""},
+	{"errors thrown from catch and finally", `
+try {
+  try {
+    throw "first"
+  } catch (e) {
+    throw e.message + " then second"
+  } finally {
+    print "inner finally"
+  }
+} catch (e) {
+  print e.message
+}
+try {
+  try {
+    throw "lost"
+  } finally {
+    throw "replaced"
+  }
+} catch (e) {
+  print e.message
+}
+`, "inner finally\nfirst then second\nreplaced\n", ""},
+	{"unknown error property", `
+try {
+  throw "x"
+} catch (e) {
+  print e.code
+}
+`, "", "TYPE_ERROR [test:5:11]: Error has no property 'code'"},
+	{"errors inside functions", `
+fn inner(list<int> xs) int {
+  return xs[5]
+}
+fn outer() int {
+  return inner([1])
+}
+print "before"
+outer()
+`, "before\n", "RUNTIME_ERROR [test:3:12]: Index 5 out of range for list of length 1"},
+	{"missing keys", `
+map<string, int> m := {"a": 1}
+print m["a"]
+print m["b"]
+`, "1\n", "RUNTIME_ERROR [test:4:8]: Key \"b\" not found in map"},
+	{"stack overflow", `
+fn r(int n) int {
+  return r(n + 1)
+}
+r(0)
+`, "", "RUNTIME_ERROR [test:3:10]: Stack overflow"},
+}
+
+func TestConformance(t *testing.T) {
+	for _, test := range conformanceTests {
+		t.Run(test.name, func(t *testing.T) {
+			treeOutput, treeErr := runConformance(test.source, false)
+			vmOutput, vmErr := runConformance(test.source, true)
+			if treeOutput != test.output {
+				t.Errorf("interpreter printed %q, want %q", treeOutput, test.output)
+			}
+			if vmOutput != treeOutput {
+				t.Errorf("bytecode printed %q, interpreter printed %q", vmOutput, treeOutput)
+			}
+			if got := errorString(treeErr); got != test.err {
+				t.Errorf("interpreter failed with %q, want %q", got, test.err)
+			}
+			//formatting the errors compares their stack traces as well
+			if treeErr != nil && vmErr != nil && FormatError(vmErr) != FormatError(treeErr) {
+				t.Errorf("bytecode failed with\n%s\ninterpreter failed with\n%s", FormatError(vmErr), FormatError(treeErr))
+			} else if errorString(vmErr) != errorString(treeErr) {
+				t.Errorf("bytecode failed with %q, interpreter failed with %q", errorString(vmErr), errorString(treeErr))
+			}
+		})
+	}
+}
+
+/*runConformance runs the source on the chosen backend, returning what it printed and the error which stopped it */
+func runConformance(source string, bytecode bool) (string, error) {
+	var out bytes.Buffer
+	runtime := New()
+	runtime.UseBytecode(bytecode)
+	runtime.SetOutput(&out)
+	_, err := runtime.EvalSource(NewSource("test", source))
+	return out.String(), err
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestREPLEcho(t *testing.T) {
+	for _, bytecode := range []bool{false, true} {
+		var out bytes.Buffer
+		runtime := New()
+		runtime.UseBytecode(bytecode)
+		runtime.UseREPL(true)
+		runtime.SetOutput(&out)
+		inputs := []string{"int x := 2", "x + 1", "fn f() {\n}\nf()\n[x, x]", "x := 5", "print x", "1 / 0", "x"}
+		for _, input := range inputs {
+			runtime.Eval(input)
+		}
+		if want := "3\n[2, 2]\n5\n5\n"; out.String() != want {
+			t.Errorf("bytecode %v printed %q, want %q", bytecode, out.String(), want)
+		}
+	}
+}
+
+/*runUnchecked runs the source without type checking it first, so the runtime's own type checks can be tested */
+func runUnchecked(source string, bytecode bool) (err error) {
+	runtime := New()
+	runtime.UseBytecode(bytecode)
+	runtime.SetOutput(&bytes.Buffer{})
+	stmts, err := Parse(NewSource("test", source))
+	if err != nil {
+		return err
+	}
+	if err := Resolve(stmts, runtime.interpreter.globals); err != nil {
+		return err
+	}
+	if runtime.machine != nil {
+		function, err := Compile(stmts, false)
+		if err != nil {
+			return err
+		}
+		_, err = runtime.machine.Run(function)
+		return err
+	}
+	defer runtime.recoverError(&err)
+	runtime.interpreter.Interpret(stmts, false)
+	return nil
+}
+
+func TestInferredTypesArePinned(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"var a := 1\na := \"x\"", "RUNTIME_ERROR [test:2:1]: TypeError -> cannot assign value to int type"},
+		{"let f := 1.5\nf := 2", "RUNTIME_ERROR [test:2:1]: TypeError -> cannot assign value to float type"},
+		{"var xs := [1]\nxs[0] := \"a\"", "RUNTIME_ERROR [test:2:3]: TypeError -> cannot assign value to int type"},
+		{"var xs := [[1]]\nappend(xs, [\"a\"])", "RUNTIME_ERROR [test:2:1]: TypeError -> cannot assign value to list<int> type"},
+		{"var m := {\"a\": 1}\nm[\"b\"] := true", "RUNTIME_ERROR [test:2:2]: TypeError -> cannot assign value to int type"},
+		{"var m := {\"a\": 1}\nm[2] := 1", "RUNTIME_ERROR [test:2:2]: TypeError -> cannot assign value to string type"},
+	}
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			for _, bytecode := range []bool{false, true} {
+				if got := errorString(runUnchecked(test.source, bytecode)); got != test.err {
+					t.Errorf("bytecode %v failed with %q, want %q", bytecode, got, test.err)
+				}
+			}
+		})
+	}
+}
@@ -1,70 +1,166 @@
-package main
+package butter
 
-/*Env is an environment object where variables can be defined */
+/*Env is an environment object where variables can be defined. The global environment stores its variables by
+  name, while the environments of blocks and function calls store them in slots, in the order they are declared,
+  so the resolver can tell the interpreter where each variable lives */
 type Env struct {
 	parent *Env
 	values map[string]Object
+	types  map[string]TypeSpec
+	//names, slots and slotTypes hold the variables of a local environment, by slot
+	names     []string
+	slots     []Object
+	slotTypes []*TypeSpec
 }
 
 /*NewEnvironment creates a new environment and initializes the array */
-func NewEnvironment(parent *Env) Env {
-	return Env{
+func NewEnvironment(parent *Env) *Env {
+	return &Env{
 		parent: parent,
 		values: make(map[string]Object),
+		types:  make(map[string]TypeSpec),
 	}
 }
 
+/*NewFrame creates a local environment whose variables are stored in slots */
+func NewFrame(parent *Env) *Env {
+	return &Env{parent: parent}
+}
+
 func (e *Env) SetParent(parent *Env) {
 	e.parent = parent
 }
 
+/*isFrame returns true for local environments, which store their variables in slots */
+func (e *Env) isFrame() bool {
+	return e.values == nil
+}
+
 func (e *Env) define(varName string, value Object) {
+	if e.isFrame() {
+		//the resolver has already reported variables declared twice in one scope
+		e.names = append(e.names, varName)
+		e.slots = append(e.slots, value)
+		e.slotTypes = append(e.slotTypes, nil)
+		return
+	}
 	_, exists := e.values[varName]
 	if exists {
-		RuntimeError("Variable '" + varName + "' already initialized in this scope")
+		runtimeError("Variable '" + varName + "' already initialized in this scope")
 	}
 	e.values[varName] = value
 }
 
+/*declare defines a variable with a declared type, which every later assignment is checked against */
+func (e *Env) declare(varName string, varType TypeSpec, value Object) {
+	e.define(varName, value)
+	if e.isFrame() {
+		e.slotTypes[len(e.slotTypes)-1] = &varType
+		return
+	}
+	e.types[varName] = varType
+}
+
 func (e *Env) assign(varName string, value Object) {
-	if found, ok := e.values[varName]; ok {
-		switch value.(type) {
-		case Integer:
-			_, ok := found.(Integer)
-			if !ok {
-				RuntimeError("Cannot assign value to int type")
-			}
-		case Float:
-			_, ok := found.(Float)
-			if !ok {
-				RuntimeError("Cannot assign value to float type")
-			}
-		case Boolean:
-			_, ok := found.(Boolean)
-			if !ok {
-				RuntimeError("Cannot assign value to bool type")
-			}
-		case String:
-			_, ok := found.(String)
-			if !ok {
-				RuntimeError("Cannot assign value to string type")
-			}
+	if e.isFrame() {
+		if slot := e.slotOf(varName); slot != -1 {
+			e.assignSlot(slot, value)
+			return
 		}
+	} else if found, ok := e.values[varName]; ok {
+		var varType *TypeSpec
+		if declared, ok := e.types[varName]; ok {
+			varType = &declared
+		}
+		CheckAssign(varType, found, value)
 		e.values[varName] = value
-	} else if e.parent != nil {
+		return
+	}
+	if e.parent != nil {
 		e.parent.assign(varName, value)
 	} else {
-		RuntimeError("Attempting to assign to undefined variable")
+		runtimeError("Attempting to assign to undefined variable")
+	}
+}
+
+/*assignSlot assigns to the variable in a slot of a local environment */
+func (e *Env) assignSlot(slot int, value Object) {
+	CheckAssign(e.slotTypes[slot], e.slots[slot], value)
+	e.slots[slot] = value
+}
+
+/*ancestor returns the environment depth levels above this one */
+func (e *Env) ancestor(depth int) *Env {
+	env := e
+	for ; depth > 0; depth-- {
+		env = env.parent
+	}
+	return env
+}
+
+/*slotOf returns the slot of the most recently declared local variable with the passed name, or -1 */
+func (e *Env) slotOf(varName string) int {
+	for slot := len(e.names) - 1; slot >= 0; slot-- {
+		if e.names[slot] == varName {
+			return slot
+		}
+	}
+	return -1
+}
+
+/*CheckAssign raises a type error if a value can't replace the current value of a variable. Variables with a
+  declared type are checked against it, others only accept values of the same kind as their current value */
+func CheckAssign(varType *TypeSpec, found Object, value Object) {
+	if varType != nil {
+		CheckVarType(*varType, value)
+		return
+	}
+	switch value.(type) {
+	case Integer:
+		_, ok := found.(Integer)
+		if !ok {
+			typeError("cannot assign value to int type")
+		}
+	case Float:
+		_, ok := found.(Float)
+		if !ok {
+			typeError("cannot assign value to float type")
+		}
+	case Boolean:
+		_, ok := found.(Boolean)
+		if !ok {
+			typeError("cannot assign value to bool type")
+		}
+	case String:
+		_, ok := found.(String)
+		if !ok {
+			typeError("cannot assign value to string type")
+		}
+	default:
+		if found.Type() != value.Type() {
+			typeError("cannot assign value to " + found.Type() + " type")
+		}
 	}
 }
 
 func (e *Env) get(varName string) Object {
-	if result, ok := e.values[varName]; ok {
+	if result, ok := e.lookup(varName); ok {
 		return result
-	} else if e.parent != nil {
-		return e.parent.get(varName)
 	}
 
-	RuntimeError("Undefined variable: '" + varName + "'")
+	runtimeError("Undefined variable: '" + varName + "'")
 	return NIL //unreachable code
 }
+
+/*lookup finds a variable in this environment or its parents, returning false if it is not defined */
+func (e *Env) lookup(varName string) (Object, bool) {
+	if slot := e.slotOf(varName); slot != -1 {
+		return e.slots[slot], true
+	}
+	if result, ok := e.values[varName]; ok {
+		return result, true
+	} else if e.parent != nil {
+		return e.parent.lookup(varName)
+	}
+	return nil, false
+}
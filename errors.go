@@ -0,0 +1,119 @@
+package butter
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*ParseError is returned when the source cannot be tokenized or parsed */
+type ParseError struct {
+	Pos     Position
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("PARSE_ERROR [%s]: %s", e.Pos, e.Message)
+}
+
+/*ParseErrors is returned when one or more syntax errors are found, holding every error in source order */
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	messages := make([]string, len(e))
+	for idx, err := range e {
+		messages[idx] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+/*RuntimeError is returned when the program performs an invalid operation while it is running */
+type RuntimeError struct {
+	Kind    string
+	Pos     Position
+	Message string
+	Trace   []Frame
+}
+
+/*Frame is a single entry of a runtime error's stack trace: a running function and the position reached in it */
+type Frame struct {
+	Function string
+	Pos      Position
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("RUNTIME_ERROR [%s]: %s", e.Pos, e.Message)
+}
+
+/*maxTraceFrames is the most stack frames FormatError will print for a single runtime error */
+const maxTraceFrames = 50
+
+/*FormatError renders an error returned by the package, following each message with the offending source
+  line underlined where it went wrong */
+func FormatError(err error) string {
+	switch e := err.(type) {
+	case ParseErrors:
+		messages := make([]string, len(e))
+		for idx, parseErr := range e {
+			messages[idx] = FormatError(parseErr)
+		}
+		return strings.Join(messages, "\n")
+	case *ParseError:
+		return withSnippet(e.Error(), e.Pos)
+	case TypeCheckErrors:
+		messages := make([]string, len(e))
+		for idx, checkErr := range e {
+			messages[idx] = FormatError(checkErr)
+		}
+		return strings.Join(messages, "\n")
+	case *TypeCheckError:
+		return withSnippet(e.Error(), e.Pos)
+	case *RuntimeError:
+		message := withSnippet(e.Error(), e.Pos)
+		if len(e.Trace) > 0 {
+			message += "\n\nstack trace:"
+			for idx, frame := range e.Trace {
+				if idx == maxTraceFrames {
+					message += fmt.Sprintf("\n...%d additional frames elided...", len(e.Trace)-idx)
+					break
+				}
+				message += fmt.Sprintf("\n%s\n\t%s", frame.Function, frame.Pos)
+			}
+		}
+		return message
+	default:
+		return err.Error()
+	}
+}
+
+func withSnippet(message string, pos Position) string {
+	if snippet := pos.Snippet(); snippet != "" {
+		return message + "\n" + snippet
+	}
+	return message
+}
+
+/*parseError stops parsing by raising a ParseError, which is recovered by the parser so it can carry on */
+func parseError(pos Position, message string) {
+	panic(&ParseError{pos, message})
+}
+
+/*runtimeError stops the execution of the program by raising a RuntimeError, the position is filled in when it is
+  caught or recovered */
+func runtimeError(message string) {
+	panic(&RuntimeError{Kind: "RuntimeError", Message: message})
+}
+
+/*indexError raises a RuntimeError for an index which is outside of the sequence it indexes */
+func indexError(message string) {
+	panic(&RuntimeError{Kind: "IndexError", Message: message})
+}
+
+/*keyError raises a RuntimeError for a key which is missing from the map it was looked up in */
+func keyError(message string) {
+	panic(&RuntimeError{Kind: "KeyError", Message: message})
+}
+
+/*typeError raises a RuntimeError for a value of the wrong type */
+func typeError(message string) {
+	panic(&RuntimeError{Kind: "TypeError", Message: "TypeError -> " + message})
+}
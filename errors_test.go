@@ -0,0 +1,128 @@
+package butter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSnippet(t *testing.T) {
+	tabbed := NewSource("test", "\tprint x")
+	wide := NewSource("test", "print \"é😀\" - 1")
+	lines := NewSource("test", "abc def\nxyz")
+	ended := NewSource("test", "print 1 +\n")
+	tests := []struct {
+		name string
+		pos  Position
+		want string
+	}{
+		{"column after a tab", Position{tabbed, 1, 8, 7, 1}, "    1 | \tprint x\n      | \t      ^"},
+		{"multibyte characters", Position{wide, 1, 7, 6, 8}, "    1 | print \"é😀\" - 1\n      |       ^~~~"},
+		{"after multibyte characters", Position{wide, 1, 12, 15, 1}, "    1 | print \"é😀\" - 1\n      |            ^"},
+		{"span running past the end of the line", Position{lines, 1, 5, 4, 7}, "    1 | abc def\n      |     ^~~"},
+		{"span past the end of the source", Position{lines, 2, 2, 9, 10}, "    2 | xyz\n      |  ^~"},
+		{"end of the source", Position{ended, 2, 1, 10, 0}, "    2 | \n      | ^"},
+		{"no source", Position{nil, 1, 1, 0, 1}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.pos.Snippet(); got != test.want {
+				t.Errorf("snippet is\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"parse error after a tab", "\tprint (1 2)", "PARSE_ERROR [test:1:11]: Expect ')' after expression\n" +
+			"    1 | \tprint (1 2)\n" +
+			"      | \t         ^"},
+		{"parse error at the end of the source", "print 1 +\n", "PARSE_ERROR [test:2:1]: Expect expression, received->EOF \n" +
+			"    2 | \n" +
+			"      | ^"},
+		{"multibyte character", "int é := 1", "PARSE_ERROR [test:1:5]: near -> 'é'\n" +
+			"    1 | int é := 1\n" +
+			"      |     ^\n" +
+			"PARSE_ERROR [test:1:7]: expect variable declaration\n" +
+			"    1 | int é := 1\n" +
+			"      |       ^~"},
+		{"type error after multibyte characters", "print \"é😀\" - 1", "TYPE_ERROR [test:1:12]: string does not support '-' operator\n" +
+			"    1 | print \"é😀\" - 1\n" +
+			"      |            ^"},
+		{"runtime error with a stack trace", "fn f() {\n\tprint [1][2]\n}\nf()", "RUNTIME_ERROR [test:2:11]: Index 2 out of range for list of length 1\n" +
+			"    2 | \tprint [1][2]\n" +
+			"      | \t         ^\n" +
+			"\n" +
+			"stack trace:\n" +
+			"f\n" +
+			"\ttest:2:11\n" +
+			"<main>\n" +
+			"\ttest:4:1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New().EvalSource(NewSource("test", test.source))
+			if got := FormatError(err); got != test.want {
+				t.Errorf("formatted as\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+/*traceString renders a stack trace as its functions and the line and column each one reached */
+func traceString(trace []Frame) string {
+	frames := make([]string, len(trace))
+	for idx, frame := range trace {
+		frames[idx] = fmt.Sprintf("%s@%d:%d", frame.Function, frame.Pos.Line, frame.Pos.Column)
+	}
+	return strings.Join(frames, " ")
+}
+
+func TestStackTrace(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"top level", "print 1 / 0", "<main>@1:9"},
+		{"nested calls", "fn a(int n) int {\n  return 10 / n\n}\nfn b() int {\n  return a(0)\n}\nprint b()", "a@2:13 b@5:10 <main>@7:7"},
+		{"methods and closures", "struct P {int a}\nfn (P p) div(int n) int {\n  fn inner() int {\n    return p.a / n\n  }\n  return inner()\n}\nP(1).div(0)", "inner@4:16 P.div@6:10 <main>@8:6"},
+		{"caught errors leave no frames behind", "fn f() {\n  throw \"x\"\n}\ntry {\n  f()\n} catch (e) {\n}\nprint [][0]", "<main>@8:9"},
+		{"uncaught throw", "fn f() {\n  throw \"x\"\n}\nf()", "f@2:3 <main>@4:1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, bytecode := range []bool{false, true} {
+				_, err := runConformance(test.source, bytecode)
+				runtimeErr, ok := err.(*RuntimeError)
+				if !ok {
+					t.Fatalf("failed with %v, want a runtime error", err)
+				}
+				if got := traceString(runtimeErr.Trace); got != test.want {
+					t.Errorf("bytecode %v traced %s, want %s", bytecode, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLongStackTrace(t *testing.T) {
+	_, err := New().EvalSource(NewSource("test", "fn r(int n) int {\n  return r(n + 1)\n}\nr(0)"))
+	runtimeErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("failed with %v, want a runtime error", err)
+	}
+	formatted := FormatError(err)
+	elided := fmt.Sprintf("\n...%d additional frames elided...", len(runtimeErr.Trace)-maxTraceFrames)
+	if !strings.HasSuffix(formatted, elided) {
+		t.Errorf("formatted stack overflow ends with %q, want %q", formatted[len(formatted)-60:], elided)
+	}
+	if printed := strings.Count(formatted, "\n\ttest:"); printed != maxTraceFrames {
+		t.Errorf("printed %d frames, want %d", printed, maxTraceFrames)
+	}
+}
@@ -1,13 +1,15 @@
-package main
+package butter
 
-/*Expr defines an object which can accept an interpreter and return an object */
+/*Expr defines an object which can accept an interpreter and return an object, and knows where it is in the source */
 type Expr interface {
 	Accept(interpreter *Interpreter) Object
+	Position() Position
 }
 
 /*Variable is an expression which will retrieve the contents of a variable from Env memory */
 type Variable struct {
 	identifier Token
+	binding    *Binding
 }
 
 /*Assign is an expr which will evaluate the righthand expression and assign it to the identifier
@@ -15,6 +17,14 @@ type Variable struct {
 type Assign struct {
 	identifier  Token
 	initializer Expr
+	binding     *Binding
+}
+
+/*Binding is where the resolver found the variable a name refers to: the slot it occupies in the environment
+  depth levels above the one it is used in. Globals have a depth of -1 and are looked up by name */
+type Binding struct {
+	depth int
+	slot  int
 }
 
 /*Binary contains a left and right subexpression, and then an operation which will
@@ -24,6 +34,13 @@ type Binary struct {
 	operator    Token
 }
 
+/*Logical contains a left and right subexpression joined by 'and' or 'or'. The right side is only
+  evaluated if the left side does not already decide the result */
+type Logical struct {
+	left, right Expr
+	operator    Token
+}
+
 /*Unary contains an operator and an expression and performs the operation on the expression */
 type Unary struct {
 	right    Expr
@@ -32,13 +49,78 @@ type Unary struct {
 
 /*Literal is an expr that returns the value of an object */
 type Literal struct {
+	pos Position
 	obj Object
 }
 
 /*Grouping is a parethetical expression that is evaluated
   before the grouping's value is returned */
 type Grouping struct {
-	expr Expr
+	paren Token
+	expr  Expr
+}
+
+/*Call evaluates the callee and then invokes it with the evaluated arguments */
+type Call struct {
+	callee Expr
+	paren  Token
+	args   []Expr
+}
+
+/*Interpolation joins its parts into a string, formatting each embedded expression with its format specifier
+  (if any). Literal text parts have an empty specifier */
+type Interpolation struct {
+	token Token
+	parts []Expr
+	specs []string
+}
+
+/*ListLiteral creates a new list from the values of its elements */
+type ListLiteral struct {
+	bracket  Token
+	elements []Expr
+}
+
+/*MapLiteral creates a new map from its entries, in order */
+type MapLiteral struct {
+	brace  Token
+	keys   []Expr
+	values []Expr
+}
+
+/*Index looks up a single element of a list or entry of a map */
+type Index struct {
+	object  Expr
+	bracket Token
+	index   Expr
+}
+
+/*SetIndex replaces a single element of a list or entry of a map */
+type SetIndex struct {
+	object  Expr
+	bracket Token
+	index   Expr
+	value   Expr
+}
+
+/*Slice copies the elements of a list between two bounds, either of which may be left out */
+type Slice struct {
+	object     Expr
+	bracket    Token
+	start, end Expr
+}
+
+/*Get looks up a named property on the value of its object expr */
+type Get struct {
+	object Expr
+	name   Token
+}
+
+/*Set assigns a value to a named field of a struct instance */
+type Set struct {
+	object Expr
+	name   Token
+	value  Expr
 }
 
 /*Accept passes assign to the visitAssign method on the interpreter */
@@ -61,6 +143,11 @@ func (b Binary) Accept(interpreter *Interpreter) Object {
 	return interpreter.visitBinary(b)
 }
 
+/*Accept finds the visitLogical method on the interpreter */
+func (l Logical) Accept(interpreter *Interpreter) Object {
+	return interpreter.visitLogical(l)
+}
+
 /*Accept finds the visitUnary method on the interpreter*/
 func (u Unary) Accept(interpreter *Interpreter) Object {
 	return interpreter.visitUnary(u)
@@ -70,3 +157,128 @@ func (u Unary) Accept(interpreter *Interpreter) Object {
 func (g Grouping) Accept(interpreter *Interpreter) Object {
 	return interpreter.visitGrouping(g)
 }
+
+/*Accept visits the visitCall method on the interpreter */
+func (c Call) Accept(interpreter *Interpreter) Object {
+	return interpreter.visitCall(c)
+}
+
+/*Accept visits the visitInterpolation method on the interpreter */
+func (s Interpolation) Accept(interpreter *Interpreter) Object {
+	return interpreter.visitInterpolation(s)
+}
+
+/*Accept visits the visitListLiteral method on the interpreter */
+func (l ListLiteral) Accept(interpreter *Interpreter) Object {
+	return interpreter.visitListLiteral(l)
+}
+
+/*Accept visits the visitMapLiteral method on the interpreter */
+func (m MapLiteral) Accept(interpreter *Interpreter) Object {
+	return interpreter.visitMapLiteral(m)
+}
+
+/*Accept visits the visitIndex method on the interpreter */
+func (idx Index) Accept(interpreter *Interpreter) Object {
+	return interpreter.visitIndex(idx)
+}
+
+/*Accept visits the visitSetIndex method on the interpreter */
+func (s SetIndex) Accept(interpreter *Interpreter) Object {
+	return interpreter.visitSetIndex(s)
+}
+
+/*Accept visits the visitSlice method on the interpreter */
+func (s Slice) Accept(interpreter *Interpreter) Object {
+	return interpreter.visitSlice(s)
+}
+
+/*Accept visits the visitGet method on the interpreter */
+func (g Get) Accept(interpreter *Interpreter) Object {
+	return interpreter.visitGet(g)
+}
+
+/*Accept visits the visitSet method on the interpreter */
+func (s Set) Accept(interpreter *Interpreter) Object {
+	return interpreter.visitSet(s)
+}
+
+/*Position returns the location of the variable being assigned to */
+func (a Assign) Position() Position {
+	return a.identifier.pos
+}
+
+/*Position returns the location of the variable's name */
+func (v Variable) Position() Position {
+	return v.identifier.pos
+}
+
+/*Position returns the location of the literal's token */
+func (l Literal) Position() Position {
+	return l.pos
+}
+
+/*Position returns the location of the binary operator */
+func (b Binary) Position() Position {
+	return b.operator.pos
+}
+
+/*Position returns the location of the logical operator */
+func (l Logical) Position() Position {
+	return l.operator.pos
+}
+
+/*Position returns the location of the unary operator */
+func (u Unary) Position() Position {
+	return u.operator.pos
+}
+
+/*Position returns the location of the opening parenthesis */
+func (g Grouping) Position() Position {
+	return g.paren.pos
+}
+
+/*Position returns the location of the expression being called */
+func (c Call) Position() Position {
+	return c.callee.Position()
+}
+
+/*Position returns the location of the property name */
+func (g Get) Position() Position {
+	return g.name.pos
+}
+
+/*Position returns the location of the field name */
+func (s Set) Position() Position {
+	return s.name.pos
+}
+
+/*Position returns the location of the whole string literal */
+func (s Interpolation) Position() Position {
+	return s.token.pos
+}
+
+/*Position returns the location of the opening bracket */
+func (l ListLiteral) Position() Position {
+	return l.bracket.pos
+}
+
+/*Position returns the location of the opening brace */
+func (m MapLiteral) Position() Position {
+	return m.brace.pos
+}
+
+/*Position returns the location of the index's opening bracket */
+func (idx Index) Position() Position {
+	return idx.bracket.pos
+}
+
+/*Position returns the location of the index's opening bracket */
+func (s SetIndex) Position() Position {
+	return s.bracket.pos
+}
+
+/*Position returns the location of the slice's opening bracket */
+func (s Slice) Position() Position {
+	return s.bracket.pos
+}
@@ -0,0 +1,260 @@
+package butter
+
+import (
+	"errors"
+	"strings"
+)
+
+/*formatIndent is the indentation added for each level of nesting */
+const formatIndent = "  "
+
+/*Format returns the source laid out in the standard style: nested lines indented by two spaces, a single space
+  around binary operators and after commas, and no more than one blank line in a row. Comments and the line
+  each statement is on are kept. Source with syntax errors is returned unchanged along with the errors */
+func Format(source *Source) (string, error) {
+	stmts, err := Parse(source)
+	if err != nil {
+		return source.Text, err
+	}
+	tokenizer := NewTokenizer(source)
+	tokenizer.KeepComments(true)
+	formatter := &formatter{text: source.Text}
+	formatted := formatter.format(tokenizer.Tokenize())
+	//only whitespace is ever changed, so the formatted program must parse to the same statements
+	reparsed, err := Parse(NewSource(source.Name, formatted))
+	if err != nil || !sameStmts(stmts, reparsed) {
+		return source.Text, errors.New("formatting " + source.Name + " would change its meaning")
+	}
+	return formatted, nil
+}
+
+func sameStmts(a []Stmt, b []Stmt) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if StmtString(a[idx]) != StmtString(b[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+/*formatter lays out a stream of tokens a line at a time */
+type formatter struct {
+	text    string
+	out     strings.Builder
+	line    []Token
+	lineNo  int
+	blank   bool
+	started bool
+	//open holds the brackets which haven't been closed yet, along with the line each was opened on
+	open []openBracket
+	//continued is true when the previous line ended with an operator, so the next line carries on its expression
+	continued bool
+}
+
+type openBracket struct {
+	token Token
+	line  int
+}
+
+func (f *formatter) format(tokens []Token) string {
+	for _, token := range tokens {
+		switch token.Type {
+		case EOF:
+		case NEWLINE:
+			f.endLine()
+		default:
+			f.line = append(f.line, token)
+		}
+	}
+	f.endLine()
+	return f.out.String()
+}
+
+/*source returns a token exactly as it was written */
+func (f *formatter) source(token Token) string {
+	return f.text[token.pos.Offset : token.pos.Offset+token.pos.Length]
+}
+
+/*endLine writes out the tokens of a line, indented by how deeply it is nested */
+func (f *formatter) endLine() {
+	f.lineNo++
+	if len(f.line) == 0 {
+		//blank lines are collapsed into one, and dropped from the start of the file
+		f.blank = f.started
+		return
+	}
+	if f.blank {
+		f.out.WriteString("\n")
+		f.blank = false
+	}
+	f.started = true
+	f.out.WriteString(strings.Repeat(formatIndent, f.indent()))
+	for idx, token := range f.line {
+		if idx > 0 && f.spaced(idx) {
+			f.out.WriteString(" ")
+		}
+		f.out.WriteString(f.source(token))
+		f.track(token)
+	}
+	f.out.WriteString("\n")
+	f.continued = continues(f.line)
+	f.line = nil
+}
+
+/*indent returns the nesting depth of the current line. Each line which opened brackets that are still open
+  adds a level, apart from those closed at the start of this line, and a line carrying on the expression of the
+  line before it is indented one level further */
+func (f *formatter) indent() int {
+	open := len(f.open)
+	for _, token := range f.line {
+		if !closing(token.Type) || open == 0 {
+			break
+		}
+		open--
+	}
+	depth := 0
+	for idx := 0; idx < open; idx++ {
+		if idx == 0 || f.open[idx].line != f.open[idx-1].line {
+			depth++
+		}
+	}
+	if f.continued {
+		depth++
+	}
+	return depth
+}
+
+/*track keeps count of the brackets which have been opened and not yet closed */
+func (f *formatter) track(token Token) {
+	switch {
+	case token.Type == LEFTGROUP || token.Type == LEFTBRACKET || token.Type == LEFTBRACE:
+		f.open = append(f.open, openBracket{token, f.lineNo})
+	case closing(token.Type) && len(f.open) > 0:
+		f.open = f.open[:len(f.open)-1]
+	}
+}
+
+func closing(tokenType TokenType) bool {
+	return tokenType == RIGHTGROUP || tokenType == RIGHTBRACKET || tokenType == RIGHTBRACE
+}
+
+/*continues returns true if a line ends with an operator, so its expression carries on to the next line */
+func continues(line []Token) bool {
+	last := line[len(line)-1]
+	if last.Type == COMMENT && len(line) > 1 {
+		last = line[len(line)-2]
+	}
+	return binaryOperator(last.Type) || last.Type == ASSIGN
+}
+
+func binaryOperator(tokenType TokenType) bool {
+	switch tokenType {
+	case PLUS, MINUS, MULT, EXP, DIV, MOD, EQUALEQUAL, BANGEQUAL, LESS, LESSEQUAL, GREATER, GREATEREQUAL, AND, OR, IN:
+		return true
+	}
+	return false
+}
+
+/*operand returns true for tokens which end an operand, after which a minus is subtraction rather than negation */
+func operand(tokenType TokenType) bool {
+	switch tokenType {
+	case IDENTIFIER, INT, FLOAT, STRING, INTERPOLATION, TRUE, FALSE, RIGHTGROUP, RIGHTBRACKET, RIGHTBRACE:
+		return true
+	}
+	return false
+}
+
+/*spaced returns true if the token at idx in the current line should be separated from the one before it */
+func (f *formatter) spaced(idx int) bool {
+	prev, token := f.line[idx-1], f.line[idx]
+	switch {
+	case token.Type == COMMENT:
+		return true
+	case prev.Type == LEFTGROUP || prev.Type == LEFTBRACKET || prev.Type == DOT:
+		return false
+	case prev.Type == LEFTBRACE || token.Type == RIGHTBRACE:
+		//braces within a line, around map entries or struct fields, keep whatever spacing they were written with
+		return prev.pos.Offset+prev.pos.Length < token.pos.Offset
+	case token.Type == SEMICOLON:
+		//the empty clauses of a for loop are kept apart, as in for ; ; {
+		return prev.Type == FOR || prev.Type == SEMICOLON
+	case token.Type == RIGHTGROUP || token.Type == RIGHTBRACKET || token.Type == COMMA || token.Type == DOT ||
+		token.Type == COLON:
+		return false
+	case prev.Type == COLON:
+		//slices are written without spaces, the colons of map entries and labels are followed by one
+		return !f.inBrackets(idx)
+	case f.unary(idx - 1):
+		return false
+	case token.Type == LEFTGROUP || token.Type == LEFTBRACKET:
+		//calls and indexes follow their operand directly
+		return !operand(prev.Type) || prev.Type == RIGHTBRACE
+	case f.typeParam(idx) || idx > 1 && f.typeParam(idx-1) && prev.Type == LESS:
+		//the angle brackets of types like list<int> are written without spaces
+		return false
+	}
+	return true
+}
+
+/*unary returns true if the token at idx in the current line is a prefix minus or bang */
+func (f *formatter) unary(idx int) bool {
+	token := f.line[idx]
+	if token.Type != MINUS && token.Type != BANG {
+		return false
+	}
+	return idx == 0 || !operand(f.line[idx-1].Type)
+}
+
+/*inBrackets returns true if the token at idx is directly inside square brackets */
+func (f *formatter) inBrackets(idx int) bool {
+	depth := 0
+	for pos := idx - 1; pos >= 0; pos-- {
+		switch f.line[pos].Type {
+		case RIGHTGROUP, RIGHTBRACKET, RIGHTBRACE:
+			depth++
+		case LEFTGROUP, LEFTBRACE:
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case LEFTBRACKET:
+			if depth == 0 {
+				return true
+			}
+			depth--
+		}
+	}
+	//the brackets may have been opened on an earlier line
+	return len(f.open) > 0 && f.open[len(f.open)-1].token.Type == LEFTBRACKET
+}
+
+/*typeParam returns true if the token at idx is one of the angle brackets of a list or map type */
+func (f *formatter) typeParam(idx int) bool {
+	token := f.line[idx]
+	if token.Type == LESS {
+		return f.line[idx-1].Type == LISTTYPE || f.line[idx-1].Type == MAPTYPE
+	}
+	if token.Type != GREATER {
+		return false
+	}
+	//walk back to the matching open bracket, which must follow list or map
+	depth := 0
+	for pos := idx - 1; pos > 0; pos-- {
+		switch f.line[pos].Type {
+		case GREATER:
+			depth++
+		case LESS:
+			if depth == 0 {
+				return f.line[pos-1].Type == LISTTYPE || f.line[pos-1].Type == MAPTYPE
+			}
+			depth--
+		case INTTYPE, FLOATTYPE, BOOLTYPE, STRINGTYPE, LISTTYPE, MAPTYPE, IDENTIFIER, COMMA:
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package butter
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"operators", "int x:=1+2*-3\nprint x**2>=4 and !false\n", "int x := 1 + 2 * -3\nprint x ** 2 >= 4 and !false\n"},
+		{"calls and indexes", "print len( [1,2,3] [0 : 2] )\n", "print len([1, 2, 3][0:2])\n"},
+		{"types", "map<string,list<int>> m:={\"a\":[1]}\n", "map<string, list<int>> m := {\"a\": [1]}\n"},
+		{"blocks", "fn f(int n)int{\nif n<2{\nreturn n\n}   else {\n return f(n-1)\n}\n}\n",
+			"fn f(int n) int {\n  if n < 2 {\n    return n\n  } else {\n    return f(n - 1)\n  }\n}\n"},
+		{"struct and method", "struct P {int a;int b}\nfn (P p)sum()int{\nreturn p.a+p.b\n}\n",
+			"struct P {int a; int b}\nfn (P p) sum() int {\n  return p.a + p.b\n}\n"},
+		{"loops and labels", "outer : for int i:=0;i<3;i:=i+1{\nfor ;; {\nbreak outer\n}\n}\n",
+			"outer: for int i := 0; i < 3; i := i + 1 {\n  for ; ; {\n    break outer\n  }\n}\n"},
+		{"blank lines", "\n\nprint 1\n\n\n\nprint 2\n", "print 1\n\nprint 2\n"},
+		{"comments", "print 1   // one\n/* two\n   lines */\nprint 2\n", "print 1 // one\n/* two\n   lines */\nprint 2\n"},
+		{"continued lines", "int x := 1 +\n2\nlist<int> xs := [1,\n2]\n", "int x := 1 +\n  2\nlist<int> xs := [1,\n  2]\n"},
+		{"unary operators before brackets", "print -(1) + !(true)\nprint - [1][0] - (2)\n", "print -(1) + !(true)\nprint -[1][0] - (2)\n"},
+		{"strings", "print \"${ 1+2 }  spaced\"\n", "print \"${ 1+2 }  spaced\"\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Format(NewSource("test", test.src))
+			if err != nil {
+				t.Fatalf("formatting %q: %v", test.src, err)
+			}
+			if got != test.want {
+				t.Errorf("formatting %q gave\n%s\nwant\n%s", test.src, got, test.want)
+			}
+			if again, _ := Format(NewSource("test", got)); again != got {
+				t.Errorf("formatting %q again changed it to\n%s", got, again)
+			}
+		})
+	}
+}
+
+func TestFormatSyntaxError(t *testing.T) {
+	src := "print (1 +\n"
+	got, err := Format(NewSource("test", src))
+	if _, ok := err.(ParseErrors); !ok {
+		t.Errorf("formatting %q returned %v, want ParseErrors", src, err)
+	}
+	if got != src {
+		t.Errorf("formatting %q returned %q, want the source unchanged", src, got)
+	}
+}
@@ -0,0 +1,99 @@
+package butter
+
+import "fmt"
+
+/*Callable is implemented by every object which can be invoked with a call expression */
+type Callable interface {
+	Object
+	Arity() int
+	Call(interpreter *Interpreter, args []Object) Object
+}
+
+/*Function is a user-defined function along with the environment it was declared in */
+type Function struct {
+	declaration FuncDeclaration
+	closure     *Env
+}
+
+/*returnValue is raised by a return statement and caught by the function call that is unwinding */
+type returnValue struct {
+	value Object
+}
+
+/*Type returns a string representation of the function object's type */
+func (f *Function) Type() string {
+	return string(FUNCOBJ)
+}
+
+/*Name returns the function's name, qualified by its receiver's type for methods */
+func (f *Function) Name() string {
+	return functionName(f.declaration)
+}
+
+/*functionName returns the name a declared function is reported by in stack traces and errors */
+func functionName(fd FuncDeclaration) string {
+	if fd.receiver != nil {
+		return fd.receiver.varType.String() + "." + fd.name.literal
+	}
+	return fd.name.literal
+}
+
+/*Bind returns a copy of a method whose environment has its receiver defined as the passed instance */
+func (f *Function) Bind(instance *Instance) Object {
+	receiver := f.declaration.receiver
+	env := NewFrame(f.closure)
+	env.declare(receiver.name.literal, receiver.varType, instance)
+	return &Function{f.declaration, env}
+}
+
+/*Declaration returns the declaration the function was created from */
+func (f *Function) Declaration() FuncDeclaration {
+	return f.declaration
+}
+
+/*Arity returns the number of arguments the function expects */
+func (f *Function) Arity() int {
+	return len(f.declaration.params)
+}
+
+/*Call binds the arguments to the parameters in a fresh environment whose parent is the closure,
+  runs the body and checks the returned value against the declared return type */
+func (f *Function) Call(interpreter *Interpreter, args []Object) Object {
+	name := f.Name()
+	env := NewFrame(f.closure)
+	for idx, param := range f.declaration.params {
+		if !IsVarType(param.varType, args[idx]) {
+			typeError(fmt.Sprintf("argument '%s' of '%s' must be of type %s", param.name.literal, name, param.varType))
+		}
+		PinType(param.varType, args[idx])
+		env.declare(param.name.literal, param.varType, args[idx])
+	}
+	interpreter.PushFrame(name)
+	//a return statement unwinds without restoring the position, so errors below are reported at the call
+	pos := interpreter.pos
+	result := f.run(interpreter, env)
+	interpreter.pos = pos
+	interpreter.PopFrame()
+	if f.declaration.returnType != nil && !IsVarType(*f.declaration.returnType, result) {
+		typeError(fmt.Sprintf("'%s' must return a value of type %s", name, f.declaration.returnType))
+	}
+	if f.declaration.returnType != nil {
+		PinType(*f.declaration.returnType, result)
+	}
+	return result
+}
+
+/*run executes the function body, catching the value handed back by a return statement */
+func (f *Function) run(interpreter *Interpreter, env *Env) (result Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			ret, ok := r.(returnValue)
+			if !ok {
+				panic(r)
+			}
+			result = ret.value
+		}
+	}()
+	interpreter.ExecuteBlock(f.declaration.body, env)
+	return NIL
+}
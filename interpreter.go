@@ -1,108 +1,484 @@
-package main
+package butter
 
 import (
 	"fmt"
+	"io"
 	"math"
+	"os"
 	"strconv"
+	"strings"
 )
 
 /*The Interpreter struct which merely holds a bunch of methods */
 type Interpreter struct {
-	env Env
+	env     *Env
+	globals *Env
+	out     io.Writer
+	pos     Position
+	frames  []callFrame
 }
 
+/*callFrame records a function call which is currently running, and where it was called from */
+type callFrame struct {
+	function string
+	callSite Position
+}
+
+/*maxCallDepth limits how deeply functions can recurse before a stack overflow is reported */
+const maxCallDepth = 10000
+
 /*NewInterpreter returns a new Interpreter object with a properly initialized environment */
-func NewInterpreter() Interpreter {
-	i := Interpreter{}
+func NewInterpreter() *Interpreter {
+	i := &Interpreter{}
 	i.env = NewEnvironment(nil)
+	i.globals = i.env
+	i.out = os.Stdout
+	DefineBuiltins(i.env)
 	return i
 }
 
-/*Interpret takes a list of parsed AST expressions and evaluates them */
-func (i *Interpreter) Interpret(stmts []Stmt, repl bool) {
+/*Interpret takes a list of parsed AST expressions and evaluates them, returning the value of the
+  final statement if it is an expression statement. In the REPL the value of every expression statement
+  is printed as well */
+func (i *Interpreter) Interpret(stmts []Stmt, repl bool) Object {
+	var last Object = NIL
 	for _, stmt := range stmts {
+		if exprStmt, ok := stmt.(ExprStmt); ok {
+			last = i.Evaluate(exprStmt.expr)
+			if repl {
+				i.Echo(last)
+			}
+			continue
+		}
 		i.Execute(stmt)
+		last = NIL
+	}
+	return last
+}
+
+/*Echo prints the value of an expression statement entered in the REPL, unless it has no value */
+func (i *Interpreter) Echo(value Object) {
+	if _, isNil := value.(Nil); !isNil {
+		fmt.Fprintln(i.out, Stringify(value))
 	}
 }
 
+/*Execute runs a statement, tracking its position so runtime errors can report where they happened */
 func (i *Interpreter) Execute(s Stmt) {
+	prevPos := i.pos
+	i.pos = s.Position()
 	s.Accept(i)
+	i.pos = prevPos
 }
 
 /*Evaluate calls the accept method on the Expr, making sure it is passed to the correct method
   back on the interpreter for evaluation */
 func (i *Interpreter) Evaluate(e Expr) Object {
-	return e.Accept(i)
+	prevPos := i.pos
+	i.pos = e.Position()
+	result := e.Accept(i)
+	i.pos = prevPos
+	return result
+}
+
+/*PushFrame records that a function has been called from the current position */
+func (i *Interpreter) PushFrame(function string) {
+	if len(i.frames) >= maxCallDepth {
+		runtimeError("Stack overflow")
+	}
+	i.frames = append(i.frames, callFrame{function, i.pos})
+}
+
+/*PopFrame removes the most recently called function once it returns */
+func (i *Interpreter) PopFrame() {
+	i.frames = i.frames[:len(i.frames)-1]
+}
+
+/*StackTrace returns the functions currently running, innermost first, along with the position
+  execution has reached within each of them */
+func (i *Interpreter) StackTrace() []Frame {
+	trace := make([]Frame, 0, len(i.frames)+1)
+	pos := i.pos
+	for idx := len(i.frames) - 1; idx >= 0; idx-- {
+		trace = append(trace, Frame{i.frames[idx].function, pos})
+		pos = i.frames[idx].callSite
+	}
+	return append(trace, Frame{"<main>", pos})
 }
 
 func (i *Interpreter) visitExprStmt(e ExprStmt) {
 	i.Evaluate(e.expr)
 }
 func (i *Interpreter) visitVarDeclaration(vd VarDeclaration) {
+	if vd.varType.Inferred() {
+		i.DeclareInferred(vd)
+		return
+	}
+	i.CheckTypeExists(vd.varType)
+	var val Object
+	if vd.initializer == nil {
+		val = ZeroValue(vd.varType)
+	} else {
+		val = i.Evaluate(vd.initializer)
+	}
+	CheckVarType(vd.varType, val)
+	i.env.declare(vd.identifier.literal, vd.varType, val)
+}
+/*DeclareInferred declares a var or let variable with the type of its initial value, so later assignments are
+  checked against it. Values like functions which have no declarable type are checked by kind instead */
+func (i *Interpreter) DeclareInferred(vd VarDeclaration) {
 	val := i.Evaluate(vd.initializer)
-	CheckVarType(vd.tokenType, val)
+	if varType := InferVarType(vd.identifier.literal, val); varType != nil {
+		i.env.declare(vd.identifier.literal, *varType, val)
+		return
+	}
 	i.env.define(vd.identifier.literal, val)
 }
+
+/*InferVarType returns the type a var or let variable takes from its initial value, pinning the value to it.
+  It returns nil for values which have no declarable type, and raises a type error for empty collections */
+func InferVarType(name string, val Object) *TypeSpec {
+	varType, ok := InferType(val)
+	if ok {
+		CheckVarType(varType, val)
+		return &varType
+	}
+	switch val.(type) {
+	case *List, *Map:
+		typeError("cannot infer the type of '" + name + "' from an empty or mixed collection, declare its type instead")
+	}
+	return nil
+}
+
 func (i *Interpreter) visitErrorStmt(e ErrorStmt) {
-	fmt.Println(e.message)
+	fmt.Fprintln(i.out, e.message)
 }
 
 /*visitAssign visits an assignment operation and then saves it to the environment variable */
 func (i *Interpreter) visitAssign(a Assign) Object {
 	val := i.Evaluate(a.initializer)
-	i.env.assign(a.identifier.literal, val)
+	if a.binding.depth < 0 {
+		i.globals.assign(a.identifier.literal, val)
+		return NIL
+	}
+	env := i.env.ancestor(a.binding.depth)
+	i.checkSlot(env, a.identifier, a.binding.slot)
+	env.assignSlot(a.binding.slot, val)
 	return NIL
 }
 
-/*visitVariable looks up a variable in the environment and returns it */
+/*visitVariable looks up a variable in the slot the resolver found it in, or by name if it is a global */
 func (i *Interpreter) visitVariable(v Variable) Object {
-	return i.env.get(v.identifier.literal)
+	if v.binding.depth < 0 {
+		return i.globals.get(v.identifier.literal)
+	}
+	env := i.env.ancestor(v.binding.depth)
+	i.checkSlot(env, v.identifier, v.binding.slot)
+	return env.slots[v.binding.slot]
+}
+
+/*checkSlot raises an error if a variable is used before its declaration has run, which can only happen when
+  a function refers to a variable declared after it and is called before that declaration */
+func (i *Interpreter) checkSlot(env *Env, name Token, slot int) {
+	if slot >= len(env.slots) {
+		runtimeError("Undefined variable: '" + name.literal + "'")
+	}
 }
 
 /*visitPrint evaluates the expr contained within a print object and then prints that */
 func (i *Interpreter) visitPrint(p Print) {
 	result := i.Evaluate(p.expr)
-	fmt.Println(Stringify(result))
+	fmt.Fprintln(i.out, Stringify(result))
 }
 
 func (i *Interpreter) visitIf(ifStmt If) {
 	condition := i.Evaluate(ifStmt.condition)
 	if res, ok := condition.(Boolean); ok {
 		if res.Value {
-			i.Execute(ifStmt.ifTrue)
+			i.ExecuteBody(ifStmt.ifTrue)
 		} else if ifStmt.ifFalse != nil {
-			i.Execute(ifStmt.ifFalse)
+			i.ExecuteBody(ifStmt.ifFalse)
 		}
 	} else {
-		RuntimeError("Cannot use non boolean value in if conditional")
+		runtimeError("Cannot use non boolean value in if conditional")
 	}
 }
 
 func (i *Interpreter) visitWhile(w While) {
-	condition := i.Evaluate(w.condition)
-	condBool, ok := condition.(Boolean)
-	if !ok {
-		RuntimeError("Cannot use non boolean value in while condition")
+	for i.LoopCondition(w.condition, "while") {
+		if i.RunLoopBody(w.body, w.label) {
+			break
+		}
 	}
-	for condBool.Value {
-		i.Execute(w.body)
-		condition := i.Evaluate(w.condition)
-		condBool, ok = condition.(Boolean)
-		if !ok {
-			RuntimeError("Cannot use non boolean value in while condition")
+}
+
+/*visitFor runs the initializer in a new scope, then loops over the body and increment for as long as
+  the condition holds */
+func (i *Interpreter) visitFor(f For) {
+	prevEnv := i.env
+	i.env = NewFrame(prevEnv)
+	defer func() { i.env = prevEnv }()
+	if f.initializer != nil {
+		i.Execute(f.initializer)
+	}
+	for f.condition == nil || i.LoopCondition(f.condition, "for") {
+		if i.RunLoopBody(f.body, f.label) {
+			break
+		}
+		if f.increment != nil {
+			i.Evaluate(f.increment)
 		}
 	}
 }
 
+/*LoopCondition evaluates the condition of a loop, which must be a boolean */
+func (i *Interpreter) LoopCondition(condition Expr, loop string) bool {
+	condBool, ok := i.Evaluate(condition).(Boolean)
+	if !ok {
+		runtimeError("Cannot use non boolean value in " + loop + " condition")
+	}
+	return condBool.Value
+}
+
+/*loopSignal is raised by break and continue statements and caught by the loop they apply to */
+type loopSignal struct {
+	isBreak bool
+	label   string
+}
+
+/*RunLoopBody executes one iteration of a loop, returning true if a break statement ended the loop */
+func (i *Interpreter) RunLoopBody(body Stmt, label string) (broke bool) {
+	pos := i.pos
+	defer func() {
+		if r := recover(); r != nil {
+			signal, ok := r.(loopSignal)
+			//signals for an outer labeled loop carry on unwinding
+			if !ok || (signal.label != "" && signal.label != label) {
+				panic(r)
+			}
+			i.pos = pos
+			broke = signal.isBreak
+		}
+	}()
+	i.ExecuteBody(body)
+	return false
+}
+
+func (i *Interpreter) visitLoopControl(l LoopControl) {
+	panic(loopSignal{l.keyword.Type == BREAK, l.label})
+}
+
 func (i *Interpreter) visitBlock(b Block) {
+	i.ExecuteBlock(b.stmts, NewFrame(i.env))
+}
+
+/*ExecuteBody runs the body of an if statement or loop. Bodies which aren't blocks still get a scope of their own */
+func (i *Interpreter) ExecuteBody(body Stmt) {
+	if _, ok := body.(Block); ok {
+		i.Execute(body)
+		return
+	}
+	i.ExecuteBlock([]Stmt{body}, NewFrame(i.env))
+}
+
+/*ExecuteBlock runs a list of statements within the passed environment, restoring the previous one afterwards */
+func (i *Interpreter) ExecuteBlock(stmts []Stmt, env *Env) {
 	prevEnv := i.env
-	i.env = NewEnvironment(&prevEnv)
+	i.env = env
 	defer func() { i.env = prevEnv }()
-	for _, stmt := range b.stmts {
+	for _, stmt := range stmts {
 		i.Execute(stmt)
 	}
 }
 
+func (i *Interpreter) visitFuncDeclaration(fd FuncDeclaration) {
+	if fd.receiver != nil {
+		i.DefineMethod(fd)
+		return
+	}
+	i.env.define(fd.name.literal, &Function{fd, i.env})
+}
+
+func (i *Interpreter) visitReturn(r Return) {
+	var value Object = NIL
+	if r.value != nil {
+		value = i.Evaluate(r.value)
+	}
+	panic(returnValue{value})
+}
+
+/*visitTry runs the try block, passing any error raised within it to the catch block. The finally block
+  runs however the try statement is left, including by return or an uncaught error */
+func (i *Interpreter) visitTry(t Try) {
+	if t.finally != nil {
+		defer i.ExecuteBlock(t.finally, NewFrame(i.env))
+	}
+	caught := i.runTry(t.body)
+	if caught == nil {
+		return
+	}
+	if t.catchName == nil {
+		panic(caught)
+	}
+	env := NewFrame(i.env)
+	env.define(t.catchName.literal, &ErrorValue{caught.Kind, caught.Message, caught.Pos, caught.Trace})
+	i.ExecuteBlock(t.catchBody, env)
+}
+
+/*runTry executes the body of a try statement, returning the error raised within it (if any) once the
+  interpreter has been unwound back to the try statement */
+func (i *Interpreter) runTry(body []Stmt) (caught *RuntimeError) {
+	pos := i.pos
+	depth := len(i.frames)
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			i.CompleteError(err)
+			i.pos = pos
+			i.frames = i.frames[:depth]
+			caught = err
+		}
+	}()
+	i.ExecuteBlock(body, NewFrame(i.env))
+	return nil
+}
+
+/*visitThrow raises the value of the throw statement as an error. Thrown errors keep the position they were
+  originally raised at, anything else becomes the message of a new error */
+func (i *Interpreter) visitThrow(t Throw) {
+	value := i.Evaluate(t.value)
+	if err, ok := value.(*ErrorValue); ok {
+		panic(&RuntimeError{err.Kind, err.Pos, err.Message, err.trace})
+	}
+	panic(&RuntimeError{Kind: "Error", Message: Stringify(value)})
+}
+
+/*CompleteError records where a newly raised error happened and the functions that were running at the time */
+func (i *Interpreter) CompleteError(err *RuntimeError) {
+	if err.Trace == nil {
+		err.Pos = i.pos
+		err.Trace = i.StackTrace()
+	}
+}
+
+/*visitListLiteral evaluates each element into a new list */
+func (i *Interpreter) visitListLiteral(l ListLiteral) Object {
+	elements := make([]Object, len(l.elements))
+	for idx, element := range l.elements {
+		elements[idx] = i.Evaluate(element)
+	}
+	return &List{elements, nil}
+}
+
+/*visitIndex looks up an element of a list or an entry of a map */
+func (i *Interpreter) visitIndex(idx Index) Object {
+	object := i.Evaluate(idx.object)
+	return IndexValue(object, i.Evaluate(idx.index))
+}
+
+/*visitSetIndex replaces an element of a list or entry of a map */
+func (i *Interpreter) visitSetIndex(s SetIndex) Object {
+	object := i.Evaluate(s.object)
+	index := i.Evaluate(s.index)
+	SetIndexValue(object, index, i.Evaluate(s.value))
+	return NIL
+}
+
+/*visitMapLiteral evaluates each entry into a new map, keeping the order they were written in */
+func (i *Interpreter) visitMapLiteral(m MapLiteral) Object {
+	result := NewMap()
+	for idx := range m.keys {
+		key := i.Evaluate(m.keys[idx])
+		result.Set(key, i.Evaluate(m.values[idx]))
+	}
+	return result
+}
+
+/*visitSlice copies the elements of a list between two bounds, either of which may be left out */
+func (i *Interpreter) visitSlice(s Slice) Object {
+	object := i.Evaluate(s.object)
+	var start, end Object
+	if s.start != nil {
+		start = i.Evaluate(s.start)
+	}
+	if s.end != nil {
+		end = i.Evaluate(s.end)
+	}
+	return SliceValue(object, start, end)
+}
+
+/*visitGet looks up a property on an object */
+func (i *Interpreter) visitGet(g Get) Object {
+	return GetProperty(i.Evaluate(g.object), g.name.literal)
+}
+
+/*visitSet assigns to a field of a struct instance */
+func (i *Interpreter) visitSet(s Set) Object {
+	object := i.Evaluate(s.object)
+	SetProperty(object, s.name.literal, i.Evaluate(s.value))
+	return NIL
+}
+
+/*visitStructDeclaration defines the struct's name as its constructor */
+func (i *Interpreter) visitStructDeclaration(sd StructDeclaration) {
+	i.env.define(sd.name.literal, &Struct{sd, make(map[string]Method)})
+	for _, field := range sd.fields {
+		i.CheckTypeExists(field.varType)
+	}
+}
+
+/*DefineMethod attaches a method to the struct named by its receiver */
+func (i *Interpreter) DefineMethod(fd FuncDeclaration) {
+	name := fd.receiver.varType.token.literal
+	structType, ok := i.LookupStruct(name)
+	if !ok {
+		typeError("method receiver '" + name + "' is not a struct")
+	}
+	structType.AddMethod(fd.name.literal, &Function{fd, i.env})
+}
+
+/*LookupStruct finds the struct with the passed name in the current environment */
+func (i *Interpreter) LookupStruct(name string) (*Struct, bool) {
+	value, ok := i.env.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	structType, ok := value.(*Struct)
+	return structType, ok
+}
+
+/*CheckTypeExists raises a type error if the type names a struct which has not been declared */
+func (i *Interpreter) CheckTypeExists(varType TypeSpec) {
+	if varType.token.Type == IDENTIFIER {
+		if _, ok := i.LookupStruct(varType.token.literal); !ok {
+			typeError("unknown type '" + varType.token.literal + "'")
+		}
+	}
+	for _, param := range varType.params {
+		i.CheckTypeExists(param)
+	}
+}
+
+/*visitCall evaluates the callee and its arguments, then invokes the callee if it is callable */
+func (i *Interpreter) visitCall(c Call) Object {
+	callee := i.Evaluate(c.callee)
+	args := make([]Object, 0, len(c.args))
+	for _, arg := range c.args {
+		args = append(args, i.Evaluate(arg))
+	}
+	function, ok := callee.(Callable)
+	if !ok {
+		runtimeError("Can only call functions, received '" + callee.Type() + "'")
+	}
+	if len(args) != function.Arity() {
+		runtimeError(fmt.Sprintf("Expected %d arguments but got %d", function.Arity(), len(args)))
+	}
+	return function.Call(i, args)
+}
+
 /*visitGrouping evaluates the internal expression and then returns that */
 func (i *Interpreter) visitGrouping(g Grouping) Object {
 	return i.Evaluate(g.expr)
@@ -113,63 +489,78 @@ func (i *Interpreter) visitGrouping(g Grouping) Object {
 func (i *Interpreter) visitBinary(b Binary) Object {
 	leftObj := i.Evaluate(b.left)
 	rightObj := i.Evaluate(b.right)
-	isNum := CheckNumberOperands(leftObj, rightObj)
-	if isNum {
-		lFloat, lIsFloat := leftObj.(Float)
-		rFloat, rIsFloat := rightObj.(Float)
-		//if either is a float, figure out which is a float and then cast to floats
-		if lIsFloat || rIsFloat {
-			if !lIsFloat {
-				leftInt := leftObj.(Integer)
-				lFloat = Float{float64(leftInt.Value)}
-			}
-			if !rIsFloat {
-				rightInt := rightObj.(Integer)
-				rFloat = Float{float64(rightInt.Value)}
-			}
-			return EvaluateFloat(lFloat, rFloat, b.operator)
-		} else {
-			//If neither are floats, they must be integers and should use integer math
-			lInteger := leftObj.(Integer)
-			rInteger := rightObj.(Integer)
-			return EvaluateInt(lInteger, rInteger, b.operator)
+	return BinaryOp(b.operator, leftObj, rightObj)
+}
+
+/*visitForIn runs the body once per element of a list or entry of a map. Each iteration binds the loop
+  variables in a fresh scope, and iterates over a snapshot so the body can modify the collection */
+func (i *Interpreter) visitForIn(f ForIn) {
+	var first, second []Object
+	switch iterable := i.Evaluate(f.iterable).(type) {
+	case *List:
+		for idx, elem := range iterable.Elements {
+			first = append(first, Integer{idx})
+			second = append(second, elem)
 		}
+		//a single loop variable takes the element rather than the index
+		if len(f.names) == 1 {
+			first = second
+		}
+	case *Map:
+		first = iterable.Keys()
+		for _, key := range first {
+			second = append(second, iterable.values[key])
+		}
+	default:
+		typeError("cannot iterate over value of type '" + iterable.Type() + "'")
 	}
-	leftBool, rightBool, isBool := CheckBoolOperands(leftObj, rightObj)
-	if isBool {
-		return EvaluateBoolean(leftBool, rightBool, b.operator)
-	}
-	if leftString, ok := leftObj.(String); ok {
-		switch b.operator.Type {
-		case PLUS:
-			return String{leftString.Value + Stringify(rightObj)}
-		default:
-			RuntimeError("string does not support '" + b.operator.Type.String() + "' operator")
+	prevEnv := i.env
+	defer func() { i.env = prevEnv }()
+	for idx := range first {
+		i.env = NewFrame(prevEnv)
+		i.env.define(f.names[0].literal, first[idx])
+		if len(f.names) == 2 {
+			i.env.define(f.names[1].literal, second[idx])
+		}
+		if i.RunLoopBody(f.body, f.label) {
+			break
 		}
 	}
-	RuntimeError("Mismatched operands: '" + leftObj.Type() + "' and '" + rightObj.Type() + "'")
-	return NIL
+}
+
+/*visitLogical evaluates the left side of an 'and' or 'or', only evaluating the right side when the left
+  side does not already decide the result */
+func (i *Interpreter) visitLogical(l Logical) Object {
+	left, ok := i.Evaluate(l.left).(Boolean)
+	if !ok {
+		typeError("cannot use non boolean value with '" + strings.ToLower(l.operator.Type.String()) + "'")
+	}
+	if l.operator.Type == OR && left.Value || l.operator.Type == AND && !left.Value {
+		return left
+	}
+	right, ok := i.Evaluate(l.right).(Boolean)
+	if !ok {
+		typeError("cannot use non boolean value with '" + strings.ToLower(l.operator.Type.String()) + "'")
+	}
+	return right
 }
 
 func (i *Interpreter) visitUnary(u Unary) Object {
-	result := i.Evaluate(u.right)
+	return UnaryOp(u.operator, i.Evaluate(u.right))
+}
 
-	switch u.operator.Type {
-	case BANG:
-		if val, ok := result.(Boolean); ok {
-			return Boolean{!val.Value}
-		}
-		RuntimeError("Cannot negate non-boolean object")
-	case MINUS:
-		if val, ok := result.(Integer); ok {
-			return Integer{-val.Value}
-		}
-		if val, ok := result.(Float); ok {
-			return Float{-val.Value}
+/*visitInterpolation evaluates each part of an interpolated string and joins them together */
+func (i *Interpreter) visitInterpolation(s Interpolation) Object {
+	var result strings.Builder
+	for idx, part := range s.parts {
+		value := i.Evaluate(part)
+		if s.specs[idx] == "" {
+			result.WriteString(Stringify(value))
+		} else {
+			result.WriteString(FormatValue(value, s.specs[idx]))
 		}
-		RuntimeError("Cannot have negative non-number type")
 	}
-	return NIL
+	return String{result.String()}
 }
 
 /*visitLiteral return sthe underlying object value of a literal */
@@ -186,7 +577,7 @@ func EvaluateFloat(left Float, right Float, operator Token) Object {
 		return Float{left.Value - right.Value}
 	case DIV:
 		if right.Value == 0 {
-			RuntimeError("Divide by zero error")
+			runtimeError("Divide by zero error")
 		} else if left.Value == 0 {
 			return Float{0}
 		}
@@ -212,7 +603,7 @@ func EvaluateFloat(left Float, right Float, operator Token) Object {
 	case LESSEQUAL:
 		return Boolean{left.Value <= right.Value}
 	default:
-		RuntimeError(fmt.Sprintf("Unsupported operation (%s) on values of type 'FLOAT'", operator.Type.String()))
+		runtimeError(fmt.Sprintf("Unsupported operation (%s) on values of type 'FLOAT'", operator.Type.String()))
 		return NIL
 	}
 }
@@ -226,14 +617,14 @@ func EvaluateInt(left Integer, right Integer, operator Token) Object {
 		return Integer{left.Value - right.Value}
 	case DIV:
 		if right.Value == 0 {
-			RuntimeError("Divide by zero error")
+			runtimeError("Divide by zero error")
 		} else if left.Value == 0 {
 			return Integer{0}
 		}
 		return Integer{left.Value / right.Value}
 	case MOD:
 		if right.Value == 0 {
-			RuntimeError("Module by zero error")
+			runtimeError("Module by zero error")
 		}
 		return Integer{left.Value % right.Value}
 	case MULT:
@@ -257,7 +648,7 @@ func EvaluateInt(left Integer, right Integer, operator Token) Object {
 	case LESSEQUAL:
 		return Boolean{left.Value <= right.Value}
 	default:
-		RuntimeError(fmt.Sprintf("Unsupported operation (%s) on values of type 'INTEGER'", operator.Type.String()))
+		runtimeError(fmt.Sprintf("Unsupported operation (%s) on values of type 'INTEGER'", operator.Type.String()))
 		return NIL
 	}
 }
@@ -265,16 +656,12 @@ func EvaluateInt(left Integer, right Integer, operator Token) Object {
 /*EvaluateBoolean returns an object based on the operations of two Boolean objects */
 func EvaluateBoolean(left Boolean, right Boolean, operator Token) Object {
 	switch operator.Type {
-	case AND:
-		return Boolean{left.Value && right.Value}
-	case OR:
-		return Boolean{left.Value || right.Value}
-	case EQUAL:
+	case EQUALEQUAL:
 		return Boolean{left.Value == right.Value}
 	case BANGEQUAL:
 		return Boolean{left.Value != right.Value}
 	default:
-		RuntimeError("Unsupported operation on values of type 'BOOLEAN'")
+		runtimeError("Unsupported operation on values of type 'BOOLEAN'")
 		return NIL
 	}
 }
@@ -297,11 +684,104 @@ func Stringify(o Object) string {
 		return "FALSE"
 	case String:
 		return t.Value
+	case *Function:
+		return "<fn " + t.Name() + ">"
+	case *Closure:
+		return "<fn " + t.function.name + ">"
+	case *BoundMethod:
+		return "<fn " + t.method.function.name + ">"
+	case *CompiledFunction:
+		return "<fn " + t.name + ">"
+	case *Builtin:
+		return "<builtin " + t.name + ">"
+	case *List:
+		elements := make([]string, len(t.Elements))
+		for idx, elem := range t.Elements {
+			elements[idx] = Inspect(elem)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *Map:
+		entries := make([]string, len(t.keys))
+		for idx, key := range t.keys {
+			entries[idx] = Inspect(key) + ": " + Inspect(t.values[key])
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case *Struct:
+		return "<struct " + t.declaration.name.literal + ">"
+	case *Instance:
+		fields := make([]string, len(t.structType.declaration.fields))
+		for idx, field := range t.structType.declaration.fields {
+			fields[idx] = field.name.literal + ": " + Inspect(t.fields[field.name.literal])
+		}
+		return t.Type() + "{" + strings.Join(fields, ", ") + "}"
+	case *ErrorValue:
+		//type errors already carry their kind in the message
+		if strings.HasPrefix(t.Message, t.Kind) {
+			return t.Message
+		}
+		return t.Kind + ": " + t.Message
 	default:
 		return "(nil)"
 	}
 }
 
+/*FormatValue formats an object according to a printf-style specifier without the leading '%', such as ".2f"
+  or "08d". A specifier without a verb pads the stringified value */
+func FormatValue(o Object, spec string) string {
+	verb := spec[len(spec)-1]
+	switch verb {
+	case 'd', 'x', 'X', 'o', 'b':
+		if val, ok := o.(Integer); ok {
+			return fmt.Sprintf("%"+spec, val.Value)
+		}
+	case 'f', 'e', 'E', 'g', 'G':
+		switch val := o.(type) {
+		case Float:
+			return fmt.Sprintf("%"+spec, val.Value)
+		case Integer:
+			return fmt.Sprintf("%"+spec, float64(val.Value))
+		}
+	case 's', 'q':
+		return fmt.Sprintf("%"+spec, Stringify(o))
+	default:
+		return fmt.Sprintf("%"+spec+"s", Stringify(o))
+	}
+	typeError(fmt.Sprintf("format '%s' cannot be used with a value of type '%s'", spec, o.Type()))
+	return ""
+}
+
+/*Contains returns true if the value is an element of a list, a key of a map or a substring of a string */
+func Contains(container Object, value Object) bool {
+	switch c := container.(type) {
+	case *List:
+		for _, elem := range c.Elements {
+			if elem == value {
+				return true
+			}
+		}
+		return false
+	case *Map:
+		_, ok := c.Get(value)
+		return ok
+	case String:
+		if str, ok := value.(String); ok {
+			return strings.Contains(c.Value, str.Value)
+		}
+		typeError("'in <string>' requires a string on the left, received '" + value.Type() + "'")
+	default:
+		typeError("'in' is not supported for '" + container.Type() + "'")
+	}
+	return false
+}
+
+/*Inspect returns the representation of an object used when it is nested in a list or map, which quotes strings */
+func Inspect(o Object) string {
+	if str, ok := o.(String); ok {
+		return strconv.Quote(str.Value)
+	}
+	return Stringify(o)
+}
+
 /*CheckNumberOperands returns a tuple with the values and a positive bool if the objects are both Integers */
 func CheckNumberOperands(left Object, right Object) bool {
 	_, lInt := left.(Integer)
@@ -318,30 +798,124 @@ func CheckBoolOperands(left Object, right Object) (Boolean, Boolean, bool) {
 	return leftBool, rightBool, lOK && rOK
 }
 
-func CheckVarType(varType Token, val Object) bool {
-	switch varType.Type {
+/*IsVarType returns true if the object can be stored in a variable of the passed type */
+func IsVarType(varType TypeSpec, val Object) bool {
+	switch varType.token.Type {
 	case INTTYPE:
-		if _, ok := val.(Integer); !ok {
-			RuntimeError("TypeError -> cannot assign value to int type")
-		}
-		return true
+		_, ok := val.(Integer)
+		return ok
 	case FLOATTYPE:
-		if _, ok := val.(Float); !ok {
-			RuntimeError("TypeError -> cannot assign value to float type")
-		}
-		return true
+		_, ok := val.(Float)
+		return ok
 	case BOOLTYPE:
-		if _, ok := val.(Boolean); !ok {
-			RuntimeError("TypeError -> cannot assign value to bool type")
+		_, ok := val.(Boolean)
+		return ok
+	case STRINGTYPE:
+		_, ok := val.(String)
+		return ok
+	case LISTTYPE:
+		list, ok := val.(*List)
+		if !ok {
+			return false
+		}
+		if list.elemType != nil {
+			return list.elemType.Equals(varType.params[0])
+		}
+		for _, elem := range list.Elements {
+			if !IsVarType(varType.params[0], elem) {
+				return false
+			}
 		}
 		return true
-	case STRINGTYPE:
-		if _, ok := val.(String); !ok {
-			RuntimeError("TypeError -> cannot assign value to string type")
+	case MAPTYPE:
+		m, ok := val.(*Map)
+		if !ok {
+			return false
+		}
+		if m.keyType != nil {
+			return m.keyType.Equals(varType.params[0]) && m.valueType.Equals(varType.params[1])
+		}
+		for key, value := range m.values {
+			if !IsVarType(varType.params[0], key) || !IsVarType(varType.params[1], value) {
+				return false
+			}
 		}
 		return true
-	default:
-		RuntimeError("TypeError -> Unknown assignment type")
+	case IDENTIFIER:
+		instance, ok := val.(*Instance)
+		return ok && instance.Type() == varType.token.literal
 	}
 	return false
 }
+
+/*PinType fixes the element types of any lists within a value which has just been stored as the passed type */
+func PinType(varType TypeSpec, val Object) {
+	if list, ok := val.(*List); ok && list.elemType == nil {
+		elemType := varType.params[0]
+		list.elemType = &elemType
+		for _, elem := range list.Elements {
+			PinType(elemType, elem)
+		}
+	}
+	if m, ok := val.(*Map); ok && m.keyType == nil {
+		keyType, valueType := varType.params[0], varType.params[1]
+		m.keyType, m.valueType = &keyType, &valueType
+		for _, value := range m.values {
+			PinType(valueType, value)
+		}
+	}
+}
+
+/*TypeName returns the name of a type keyword as it is written in source */
+func TypeName(varType Token) string {
+	switch varType.Type {
+	case INTTYPE:
+		return "int"
+	case FLOATTYPE:
+		return "float"
+	case BOOLTYPE:
+		return "bool"
+	case STRINGTYPE:
+		return "string"
+	case LISTTYPE:
+		return "list"
+	case MAPTYPE:
+		return "map"
+	case IDENTIFIER:
+		return varType.literal
+	default:
+		return varType.Type.String()
+	}
+}
+
+/*CheckVarType raises a type error if the value cannot be stored as the passed type, otherwise pins the
+  value to that type */
+func CheckVarType(varType TypeSpec, val Object) bool {
+	if !IsVarType(varType, val) {
+		typeError("cannot assign value to " + varType.String() + " type")
+	}
+	PinType(varType, val)
+	return true
+}
+
+/*ZeroValue returns the value a variable of the passed type starts with when it has no initializer */
+func ZeroValue(varType TypeSpec) Object {
+	switch varType.token.Type {
+	case INTTYPE:
+		return Integer{0}
+	case FLOATTYPE:
+		return Float{0}
+	case BOOLTYPE:
+		return Boolean{false}
+	case STRINGTYPE:
+		return String{""}
+	case LISTTYPE:
+		elemType := varType.params[0]
+		return &List{[]Object{}, &elemType}
+	case MAPTYPE:
+		m := NewMap()
+		PinType(varType, m)
+		return m
+	}
+	return NIL
+}
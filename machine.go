@@ -0,0 +1,635 @@
+package butter
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*Closure is a compiled function along with the variables it captured from the functions enclosing it */
+type Closure struct {
+	function *CompiledFunction
+	upvalues []*upvalue
+}
+
+/*Type returns a string representation of the closure's type */
+func (c *Closure) Type() string {
+	return string(FUNCOBJ)
+}
+
+/*Declaration returns the declaration the closure's function was compiled from */
+func (c *Closure) Declaration() FuncDeclaration {
+	return c.function.declaration
+}
+
+/*Bind returns the method bound to the instance it was looked up on */
+func (c *Closure) Bind(instance *Instance) Object {
+	return &BoundMethod{instance, c}
+}
+
+/*BoundMethod is a compiled method along with the instance it is called on, which is passed as its receiver */
+type BoundMethod struct {
+	receiver *Instance
+	method   *Closure
+}
+
+/*Type returns a string representation of the bound method's type */
+func (b *BoundMethod) Type() string {
+	return string(FUNCOBJ)
+}
+
+/*upvalue is a variable captured by a closure. It refers to a slot on the stack until the variable goes out of
+  scope, then it is closed over and keeps the value (and declared type) itself */
+type upvalue struct {
+	slot    int
+	open    bool
+	value   Object
+	varType *TypeSpec
+}
+
+/*undeclared is the placeholder held by a local variable's slot until its declaration runs */
+type undeclared struct {
+	name string
+}
+
+/*Type returns a string representation of the placeholder's type */
+func (u *undeclared) Type() string {
+	return string(NILOBJ)
+}
+
+/*iterator steps through a snapshot of the collection of a for-in loop */
+type iterator struct {
+	first, second []Object
+	next          int
+}
+
+/*Type returns a string representation of the iterator's type */
+func (it *iterator) Type() string {
+	return "Iterator"
+}
+
+/*machineFrame is a running call of a closure, whose locals start at base on the stack */
+type machineFrame struct {
+	closure *Closure
+	ip      int
+	base    int
+}
+
+/*handler is an active try block, recording what to unwind to when an error is caught */
+type handler struct {
+	frame int
+	stack int
+	ip    int
+}
+
+/*Machine is a stack based virtual machine which runs the bytecode produced by the Compiler. Globals are kept
+  in the interpreter's environment so both can run code in the same session */
+type Machine struct {
+	interpreter  *Interpreter
+	stack        []Object
+	types        []*TypeSpec
+	frames       []*machineFrame
+	handlers     []handler
+	openUpvalues []*upvalue
+}
+
+/*NewMachine returns a machine sharing the globals and output of the passed interpreter */
+func NewMachine(interpreter *Interpreter) *Machine {
+	return &Machine{interpreter: interpreter}
+}
+
+/*Run runs a compiled program, returning the value it hands back or the runtime error which stopped it */
+func (m *Machine) Run(function *CompiledFunction) (Object, error) {
+	m.push(&Closure{function, nil})
+	m.frames = append(m.frames, &machineFrame{m.stack[len(m.stack)-1].(*Closure), 0, len(m.stack) - 1})
+	for {
+		result, err, done := m.runProtected()
+		if done {
+			return result, err
+		}
+	}
+}
+
+/*runProtected runs until the program finishes or an error is raised. Errors raised within a try block are
+  handed to the block's handler, and running carries on from there */
+func (m *Machine) runProtected() (result Object, err error, done bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeErr, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			m.completeError(runtimeErr)
+			if len(m.handlers) > 0 {
+				m.handle(runtimeErr)
+				return
+			}
+			m.reset()
+			result, err, done = NIL, runtimeErr, true
+		}
+	}()
+	return m.execute(), nil, true
+}
+
+/*completeError records where a newly raised error happened and the functions that were running at the time */
+func (m *Machine) completeError(err *RuntimeError) {
+	if err.Trace == nil {
+		err.Pos = m.position(m.frames[len(m.frames)-1])
+		err.Trace = m.stackTrace()
+	}
+}
+
+/*stackTrace returns the functions currently running, innermost first, along with the position execution has
+  reached within each of them */
+func (m *Machine) stackTrace() []Frame {
+	trace := make([]Frame, 0, len(m.frames))
+	for idx := len(m.frames) - 1; idx >= 0; idx-- {
+		frame := m.frames[idx]
+		trace = append(trace, Frame{frame.closure.function.name, m.position(frame)})
+	}
+	return trace
+}
+
+/*position returns the source position of the instruction a frame is running */
+func (m *Machine) position(frame *machineFrame) Position {
+	positions := frame.closure.function.chunk.positions
+	if frame.ip == 0 || len(positions) == 0 {
+		return Position{}
+	}
+	return positions[frame.ip-1]
+}
+
+/*handle unwinds to the innermost try block and jumps to its handler, with the caught error on the stack */
+func (m *Machine) handle(err *RuntimeError) {
+	h := m.handlers[len(m.handlers)-1]
+	m.handlers = m.handlers[:len(m.handlers)-1]
+	m.closeUpvalues(h.stack)
+	m.stack = m.stack[:h.stack]
+	m.frames = m.frames[:h.frame]
+	m.push(&ErrorValue{err.Kind, err.Message, err.Pos, err.Trace})
+	m.setType(len(m.stack)-1, nil)
+	m.frames[len(m.frames)-1].ip = h.ip
+}
+
+/*reset discards everything left over from a program stopped by an error */
+func (m *Machine) reset() {
+	m.stack = m.stack[:0]
+	m.frames = nil
+	m.handlers = nil
+	m.openUpvalues = nil
+}
+
+func (m *Machine) push(value Object) {
+	m.stack = append(m.stack, value)
+}
+
+func (m *Machine) pop() Object {
+	value := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return value
+}
+
+func (m *Machine) peek() Object {
+	return m.stack[len(m.stack)-1]
+}
+
+/*setType records the declared type of the variable in a stack slot, nil for variables without one */
+func (m *Machine) setType(slot int, varType *TypeSpec) {
+	for len(m.types) <= slot {
+		m.types = append(m.types, nil)
+	}
+	m.types[slot] = varType
+}
+
+func (m *Machine) typeOf(slot int) *TypeSpec {
+	if slot < len(m.types) {
+		return m.types[slot]
+	}
+	return nil
+}
+
+func (f *machineFrame) readByte() int {
+	b := f.closure.function.chunk.code[f.ip]
+	f.ip++
+	return int(b)
+}
+
+func (f *machineFrame) readShort() int {
+	value := f.closure.function.chunk.readShort(f.ip)
+	f.ip += 2
+	return value
+}
+
+func (f *machineFrame) readString() string {
+	return f.closure.function.chunk.constants[f.readShort()].(String).Value
+}
+
+/*execute runs instructions until the outermost frame returns */
+func (m *Machine) execute() Object {
+	frame := m.frames[len(m.frames)-1]
+	for {
+		chunk := frame.closure.function.chunk
+		op := Opcode(frame.readByte())
+		switch op {
+		case OpConstant:
+			m.push(chunk.constants[frame.readShort()])
+		case OpNil:
+			m.push(NIL)
+		case OpTrue:
+			m.push(Boolean{true})
+		case OpFalse:
+			m.push(Boolean{false})
+		case OpPop:
+			m.pop()
+		case OpPrint:
+			fmt.Fprintln(m.interpreter.out, Stringify(m.pop()))
+		case OpEcho:
+			m.interpreter.Echo(m.peek())
+		case OpDefineGlobal:
+			name := frame.readString()
+			m.defineGlobal(name, frame.readShort(), chunk, m.pop())
+		case OpGetGlobal:
+			m.push(m.interpreter.env.get(frame.readString()))
+		case OpSetGlobal:
+			m.interpreter.env.assign(frame.readString(), m.pop())
+			m.push(NIL)
+		case OpDeclareLocal:
+			name := frame.readString()
+			typeOperand := frame.readShort()
+			m.declareLocal(name, typeOperand, chunk, frame.base+frame.readShort())
+		case OpGetLocal:
+			m.push(m.stack[frame.base+frame.readShort()])
+		case OpSetLocal:
+			slot := frame.base + frame.readShort()
+			value := m.pop()
+			CheckAssign(m.typeOf(slot), m.stack[slot], value)
+			m.stack[slot] = value
+			m.push(NIL)
+		case OpGetUpvalue:
+			m.push(m.getUpvalue(frame.closure.upvalues[frame.readShort()]))
+		case OpSetUpvalue:
+			m.setUpvalue(frame.closure.upvalues[frame.readShort()], m.pop())
+			m.push(NIL)
+		case OpCloseUpvalue:
+			m.closeUpvalues(len(m.stack) - 1)
+			m.pop()
+		case OpBinary:
+			operator := TokenType(frame.readByte())
+			right := m.pop()
+			m.stack[len(m.stack)-1] = binaryOp(operator, m.peek(), right)
+		case OpUnary:
+			operator := Token{Type: TokenType(frame.readByte())}
+			m.stack[len(m.stack)-1] = UnaryOp(operator, m.peek())
+		case OpLogical:
+			operator := TokenType(frame.readByte())
+			offset := frame.readShort()
+			left := checkLogical(operator, m.peek())
+			if operator == OR && left.Value || operator == AND && !left.Value {
+				frame.ip += offset
+			} else {
+				m.pop()
+			}
+		case OpCheckBool:
+			checkLogical(TokenType(frame.readByte()), m.peek())
+		case OpJump:
+			offset := frame.readShort()
+			frame.ip += offset
+		case OpJumpIfFalse:
+			kind := frame.readByte()
+			offset := frame.readShort()
+			condition, ok := m.pop().(Boolean)
+			if !ok {
+				runtimeError(conditionMessages[kind])
+			}
+			if !condition.Value {
+				frame.ip += offset
+			}
+		case OpLoop:
+			offset := frame.readShort()
+			frame.ip -= offset
+		case OpCall:
+			m.call(frame.readByte())
+			frame = m.frames[len(m.frames)-1]
+		case OpClosure:
+			function := chunk.constants[frame.readShort()].(*CompiledFunction)
+			closure := &Closure{function, make([]*upvalue, function.upvalueCount)}
+			for idx := range closure.upvalues {
+				isLocal := frame.readByte()
+				index := frame.readShort()
+				if isLocal == 1 {
+					closure.upvalues[idx] = m.captureUpvalue(frame.base + index)
+				} else {
+					closure.upvalues[idx] = frame.closure.upvalues[index]
+				}
+			}
+			m.push(closure)
+		case OpReturn:
+			result := m.pop()
+			m.closeUpvalues(frame.base)
+			m.stack = m.stack[:frame.base]
+			m.frames = m.frames[:len(m.frames)-1]
+			if len(m.frames) == 0 {
+				return result
+			}
+			returned := frame.closure.function
+			frame = m.frames[len(m.frames)-1]
+			if returnType := returned.declaration.returnType; returnType != nil {
+				if !IsVarType(*returnType, result) {
+					typeError(fmt.Sprintf("'%s' must return a value of type %s", returned.name, returnType))
+				}
+				PinType(*returnType, result)
+			}
+			m.push(result)
+		case OpList:
+			count := frame.readShort()
+			elements := make([]Object, count)
+			copy(elements, m.stack[len(m.stack)-count:])
+			m.stack = m.stack[:len(m.stack)-count]
+			m.push(&List{elements, nil})
+		case OpMap:
+			count := frame.readShort()
+			entries := m.stack[len(m.stack)-2*count:]
+			result := NewMap()
+			for idx := 0; idx < len(entries); idx += 2 {
+				result.Set(entries[idx], entries[idx+1])
+			}
+			m.stack = m.stack[:len(m.stack)-2*count]
+			m.push(result)
+		case OpGetIndex:
+			index := m.pop()
+			m.stack[len(m.stack)-1] = IndexValue(m.peek(), index)
+		case OpSetIndex:
+			value := m.pop()
+			index := m.pop()
+			SetIndexValue(m.peek(), index, value)
+			m.stack[len(m.stack)-1] = NIL
+		case OpSlice:
+			flags := frame.readByte()
+			var start, end Object
+			if flags&2 != 0 {
+				end = m.pop()
+			}
+			if flags&1 != 0 {
+				start = m.pop()
+			}
+			m.stack[len(m.stack)-1] = SliceValue(m.peek(), start, end)
+		case OpGetProperty:
+			name := frame.readString()
+			m.stack[len(m.stack)-1] = GetProperty(m.peek(), name)
+		case OpSetProperty:
+			name := frame.readString()
+			value := m.pop()
+			SetProperty(m.peek(), name, value)
+			m.stack[len(m.stack)-1] = NIL
+		case OpStruct:
+			template := chunk.constants[frame.readShort()].(*Struct)
+			m.push(&Struct{template.declaration, make(map[string]Method)})
+		case OpMethod:
+			name := frame.readString()
+			method := m.pop().(*Closure)
+			structType, ok := m.pop().(*Struct)
+			if !ok {
+				typeError("method receiver '" + method.function.declaration.receiver.varType.token.literal + "' is not a struct")
+			}
+			structType.AddMethod(name, method)
+		case OpStringify:
+			m.stack[len(m.stack)-1] = String{Stringify(m.peek())}
+		case OpFormat:
+			spec := frame.readString()
+			m.stack[len(m.stack)-1] = String{FormatValue(m.peek(), spec)}
+		case OpConcat:
+			count := frame.readShort()
+			var result strings.Builder
+			for _, part := range m.stack[len(m.stack)-count:] {
+				result.WriteString(part.(String).Value)
+			}
+			m.stack = m.stack[:len(m.stack)-count]
+			m.push(String{result.String()})
+		case OpThrow:
+			value := m.pop()
+			if err, ok := value.(*ErrorValue); ok {
+				panic(&RuntimeError{err.Kind, err.Pos, err.Message, err.trace})
+			}
+			panic(&RuntimeError{Kind: "Error", Message: Stringify(value)})
+		case OpTry:
+			offset := frame.readShort()
+			m.handlers = append(m.handlers, handler{len(m.frames), len(m.stack), frame.ip + offset})
+		case OpEndTry:
+			m.handlers = m.handlers[:len(m.handlers)-1]
+		case OpIterStart:
+			m.stack[len(m.stack)-1] = newIterator(m.peek(), frame.readByte())
+		case OpIterNext:
+			it := m.stack[frame.base+frame.readShort()].(*iterator)
+			names := frame.readByte()
+			offset := frame.readShort()
+			if it.next == len(it.first) {
+				frame.ip += offset
+				continue
+			}
+			m.push(it.first[it.next])
+			m.setType(len(m.stack)-1, nil)
+			if names == 2 {
+				m.push(it.second[it.next])
+				m.setType(len(m.stack)-1, nil)
+			}
+			it.next++
+		default:
+			runtimeError(fmt.Sprintf("Unknown opcode %s", op))
+		}
+	}
+}
+
+/*binaryOp performs a binary operator, taking a shortcut for the integer arithmetic and comparisons loops
+  spend most of their time on */
+func binaryOp(operator TokenType, left Object, right Object) Object {
+	if l, ok := left.(Integer); ok {
+		if r, ok := right.(Integer); ok {
+			switch operator {
+			case PLUS:
+				return Integer{l.Value + r.Value}
+			case MINUS:
+				return Integer{l.Value - r.Value}
+			case LESS:
+				return Boolean{l.Value < r.Value}
+			case LESSEQUAL:
+				return Boolean{l.Value <= r.Value}
+			case GREATER:
+				return Boolean{l.Value > r.Value}
+			case GREATEREQUAL:
+				return Boolean{l.Value >= r.Value}
+			case EQUALEQUAL:
+				return Boolean{l.Value == r.Value}
+			}
+		}
+	}
+	return BinaryOp(Token{Type: operator}, left, right)
+}
+
+/*checkLogical raises a type error if an operand of 'and' or 'or' is not a boolean */
+func checkLogical(operator TokenType, operand Object) Boolean {
+	value, ok := operand.(Boolean)
+	if !ok {
+		typeError("cannot use non boolean value with '" + strings.ToLower(operator.String()) + "'")
+	}
+	return value
+}
+
+/*newIterator snapshots a list or map for a for-in loop with the passed number of loop variables */
+func newIterator(iterable Object, names int) *iterator {
+	it := &iterator{}
+	switch iterable := iterable.(type) {
+	case *List:
+		for idx, elem := range iterable.Elements {
+			it.first = append(it.first, Integer{idx})
+			it.second = append(it.second, elem)
+		}
+		//a single loop variable takes the element rather than the index
+		if names == 1 {
+			it.first = it.second
+		}
+	case *Map:
+		it.first = iterable.Keys()
+		for _, key := range it.first {
+			it.second = append(it.second, iterable.values[key])
+		}
+	default:
+		typeError("cannot iterate over value of type '" + iterable.Type() + "'")
+	}
+	return it
+}
+
+/*defineGlobal stores a value as a new global, checked against its declared type like the interpreter does */
+func (m *Machine) defineGlobal(name string, typeOperand int, chunk *Chunk, value Object) {
+	env := m.interpreter.env
+	switch typeOperand {
+	case typeUntyped:
+		env.define(name, value)
+	case typeInferred:
+		if varType := InferVarType(name, value); varType != nil {
+			env.declare(name, *varType, value)
+		} else {
+			env.define(name, value)
+		}
+	default:
+		varType := chunk.types[typeOperand]
+		CheckVarType(varType, value)
+		env.declare(name, varType, value)
+	}
+}
+
+/*declareLocal moves the value on top of the stack into the slot reserved for a local, checking it against the
+  local's declared type */
+func (m *Machine) declareLocal(name string, typeOperand int, chunk *Chunk, slot int) {
+	value := m.pop()
+	switch typeOperand {
+	case typeUntyped:
+		m.setType(slot, nil)
+	case typeInferred:
+		m.setType(slot, InferVarType(name, value))
+	default:
+		varType := &chunk.types[typeOperand]
+		CheckVarType(*varType, value)
+		m.setType(slot, varType)
+	}
+	m.stack[slot] = value
+}
+
+/*call invokes the value below the arguments on top of the stack. Closures get a new frame, anything else
+  callable is run straight away and its result replaces the callee */
+func (m *Machine) call(argCount int) {
+	calleeSlot := len(m.stack) - argCount - 1
+	callee := m.stack[calleeSlot]
+	switch callee := callee.(type) {
+	case *Closure:
+		m.callClosure(callee, calleeSlot, argCount)
+		m.setType(calleeSlot, nil)
+	case *BoundMethod:
+		m.stack[calleeSlot] = callee.receiver
+		m.callClosure(callee.method, calleeSlot, argCount)
+		m.setType(calleeSlot, &callee.method.function.declaration.receiver.varType)
+	case Callable:
+		if argCount != callee.Arity() {
+			runtimeError(fmt.Sprintf("Expected %d arguments but got %d", callee.Arity(), argCount))
+		}
+		args := make([]Object, argCount)
+		copy(args, m.stack[calleeSlot+1:])
+		result := callee.Call(m.interpreter, args)
+		m.stack = m.stack[:calleeSlot]
+		m.push(result)
+	default:
+		runtimeError("Can only call functions, received '" + callee.Type() + "'")
+	}
+}
+
+/*callClosure checks the arguments against the function's parameters and pushes a frame to run it */
+func (m *Machine) callClosure(closure *Closure, base int, argCount int) {
+	function := closure.function
+	if argCount != function.arity {
+		runtimeError(fmt.Sprintf("Expected %d arguments but got %d", function.arity, argCount))
+	}
+	for idx := range function.declaration.params {
+		param := &function.declaration.params[idx]
+		arg := m.stack[base+1+idx]
+		if !IsVarType(param.varType, arg) {
+			typeError(fmt.Sprintf("argument '%s' of '%s' must be of type %s", param.name.literal, function.name, param.varType))
+		}
+		PinType(param.varType, arg)
+		m.setType(base+1+idx, &param.varType)
+	}
+	//the main frame is not counted, matching the interpreter's call depth
+	if len(m.frames) > maxCallDepth {
+		runtimeError("Stack overflow")
+	}
+	m.frames = append(m.frames, &machineFrame{closure, 0, base})
+}
+
+/*captureUpvalue returns the upvalue for a stack slot, reusing it if another closure has already captured it */
+func (m *Machine) captureUpvalue(slot int) *upvalue {
+	for _, open := range m.openUpvalues {
+		if open.slot == slot {
+			return open
+		}
+	}
+	created := &upvalue{slot: slot, open: true}
+	m.openUpvalues = append(m.openUpvalues, created)
+	return created
+}
+
+/*closeUpvalues moves the variables in every slot from the passed one upwards off the stack and into the
+  upvalues which captured them */
+func (m *Machine) closeUpvalues(from int) {
+	remaining := m.openUpvalues[:0]
+	for _, open := range m.openUpvalues {
+		if open.slot >= from {
+			open.value, open.varType = m.stack[open.slot], m.typeOf(open.slot)
+			open.open = false
+		} else {
+			remaining = append(remaining, open)
+		}
+	}
+	m.openUpvalues = remaining
+}
+
+/*getUpvalue reads a captured variable, which may not have been declared yet if it was declared after the
+  function using it */
+func (m *Machine) getUpvalue(uv *upvalue) Object {
+	value := uv.value
+	if uv.open {
+		value = m.stack[uv.slot]
+	}
+	if placeholder, ok := value.(*undeclared); ok {
+		runtimeError("Undefined variable: '" + placeholder.name + "'")
+	}
+	return value
+}
+
+/*setUpvalue assigns to a captured variable, checking the value like any other assignment */
+func (m *Machine) setUpvalue(uv *upvalue, value Object) {
+	m.getUpvalue(uv)
+	if uv.open {
+		CheckAssign(m.typeOf(uv.slot), m.stack[uv.slot], value)
+		m.stack[uv.slot] = value
+		return
+	}
+	CheckAssign(uv.varType, uv.value, value)
+	uv.value = value
+}
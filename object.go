@@ -1,4 +1,4 @@
-package main
+package butter
 
 /*ObjType provides an enum-like definition of objects */
 type ObjType string
@@ -9,6 +9,12 @@ const (
 	BOOLEANOBJ ObjType = "Boolean"
 	STRINGOBJ  ObjType = "String"
 	NILOBJ     ObjType = "Nil"
+	FUNCOBJ    ObjType = "Function"
+	ERROROBJ   ObjType = "Error"
+	LISTOBJ    ObjType = "List"
+	MAPOBJ     ObjType = "Map"
+	STRUCTOBJ  ObjType = "Struct"
+	BUILTINOBJ ObjType = "Builtin"
 )
 
 /*Object defines a common object interface which all variable types will implement */
@@ -65,5 +71,101 @@ func (n Nil) Type() string {
 	return string(NILOBJ)
 }
 
+/*List is a mutable, ordered sequence of objects. Once a list is stored in a typed variable its element type is
+  pinned, and every element added to it afterwards must be of that type */
+type List struct {
+	Elements []Object
+	elemType *TypeSpec
+}
+
+/*Type returns a string representation of the list object's type */
+func (l *List) Type() string {
+	return string(LISTOBJ)
+}
+
+/*Map is a mutable collection of entries which remembers the order its keys were first inserted in. Keys must be
+  integers, floats, strings or booleans. Like lists, maps stored in typed variables have their key and value
+  types pinned */
+type Map struct {
+	keys      []Object
+	values    map[Object]Object
+	keyType   *TypeSpec
+	valueType *TypeSpec
+}
+
+/*NewMap returns an empty map which accepts any key and value types */
+func NewMap() *Map {
+	return &Map{values: make(map[Object]Object)}
+}
+
+/*Type returns a string representation of the map object's type */
+func (m *Map) Type() string {
+	return string(MAPOBJ)
+}
+
+/*Len returns the number of entries in the map */
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+/*Keys returns the keys of the map in insertion order */
+func (m *Map) Keys() []Object {
+	return append([]Object{}, m.keys...)
+}
+
+/*Get returns the value stored under a key, and whether there was one */
+func (m *Map) Get(key Object) (Object, bool) {
+	CheckHashable(key)
+	value, ok := m.values[key]
+	return value, ok
+}
+
+/*Set stores a value under a key, adding the key to the end of the order if it is new */
+func (m *Map) Set(key Object, value Object) {
+	CheckHashable(key)
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+/*Delete removes a key and its value, returning true if the key was present */
+func (m *Map) Delete(key Object) bool {
+	CheckHashable(key)
+	if _, ok := m.values[key]; !ok {
+		return false
+	}
+	delete(m.values, key)
+	for idx, existing := range m.keys {
+		if existing == key {
+			m.keys = append(m.keys[:idx], m.keys[idx+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+/*CheckHashable raises a type error if the object cannot be used as a map key */
+func CheckHashable(key Object) {
+	switch key.(type) {
+	case Integer, Float, String, Boolean:
+		return
+	}
+	typeError("unhashable map key of type '" + key.Type() + "'")
+}
+
+/*ErrorValue is the object bound by a catch block, describing an error which was thrown */
+type ErrorValue struct {
+	Kind    string
+	Message string
+	Pos     Position
+	trace   []Frame
+}
+
+/*Type returns a string representation of the error object's type */
+func (e *ErrorValue) Type() string {
+	return string(ERROROBJ)
+}
+
 /*NIL is a singleton which all nil objects will reference */
 var NIL Nil
@@ -0,0 +1,13 @@
+package butter
+
+import "testing"
+
+func TestUnhashableKey(t *testing.T) {
+	defer func() {
+		err, ok := recover().(*RuntimeError)
+		if !ok || err.Kind != "TypeError" || err.Message != "TypeError -> unhashable map key of type 'List'" {
+			t.Errorf("setting a list key raised %v, want a type error", err)
+		}
+	}()
+	NewMap().Set(&List{Elements: []Object{Integer{1}}}, Integer{2})
+}
@@ -0,0 +1,210 @@
+package butter
+
+import "fmt"
+
+/*BinaryOp performs an arithmetic, comparison or membership operator on two values which have already been
+  evaluated. It is shared by the interpreter and the bytecode machine so both raise the same errors */
+func BinaryOp(operator Token, leftObj Object, rightObj Object) Object {
+	if operator.Type == IN {
+		return Boolean{Contains(rightObj, leftObj)}
+	}
+	isNum := CheckNumberOperands(leftObj, rightObj)
+	if isNum {
+		lFloat, lIsFloat := leftObj.(Float)
+		rFloat, rIsFloat := rightObj.(Float)
+		//if either is a float, figure out which is a float and then cast to floats
+		if lIsFloat || rIsFloat {
+			if !lIsFloat {
+				leftInt := leftObj.(Integer)
+				lFloat = Float{float64(leftInt.Value)}
+			}
+			if !rIsFloat {
+				rightInt := rightObj.(Integer)
+				rFloat = Float{float64(rightInt.Value)}
+			}
+			return EvaluateFloat(lFloat, rFloat, operator)
+		}
+		//If neither are floats, they must be integers and should use integer math
+		lInteger := leftObj.(Integer)
+		rInteger := rightObj.(Integer)
+		return EvaluateInt(lInteger, rInteger, operator)
+	}
+	leftBool, rightBool, isBool := CheckBoolOperands(leftObj, rightObj)
+	if isBool {
+		return EvaluateBoolean(leftBool, rightBool, operator)
+	}
+	if leftString, ok := leftObj.(String); ok {
+		switch operator.Type {
+		case PLUS:
+			return String{leftString.Value + Stringify(rightObj)}
+		default:
+			runtimeError("string does not support '" + operator.Type.String() + "' operator")
+		}
+	}
+	runtimeError("Mismatched operands: '" + leftObj.Type() + "' and '" + rightObj.Type() + "'")
+	return NIL
+}
+
+/*UnaryOp performs a negation on a value which has already been evaluated */
+func UnaryOp(operator Token, result Object) Object {
+	switch operator.Type {
+	case BANG:
+		if val, ok := result.(Boolean); ok {
+			return Boolean{!val.Value}
+		}
+		runtimeError("Cannot negate non-boolean object")
+	case MINUS:
+		if val, ok := result.(Integer); ok {
+			return Integer{-val.Value}
+		}
+		if val, ok := result.(Float); ok {
+			return Float{-val.Value}
+		}
+		runtimeError("Cannot have negative non-number type")
+	}
+	return NIL
+}
+
+/*IndexValue looks up an element of a list, counting from the end for negative indexes, or an entry of a map */
+func IndexValue(object Object, index Object) Object {
+	switch object := object.(type) {
+	case *List:
+		return object.Elements[ListIndex(index, len(object.Elements))]
+	case *Map:
+		value, ok := object.Get(index)
+		if !ok {
+			keyError("Key " + Inspect(index) + " not found in map")
+		}
+		return value
+	default:
+		typeError("cannot index value of type '" + object.Type() + "'")
+		return NIL
+	}
+}
+
+/*SetIndexValue replaces an element of a list or entry of a map, checking the value against the
+  container's pinned types */
+func SetIndexValue(object Object, index Object, value Object) {
+	switch object := object.(type) {
+	case *List:
+		idx := ListIndex(index, len(object.Elements))
+		if object.elemType != nil {
+			CheckVarType(*object.elemType, value)
+		}
+		object.Elements[idx] = value
+	case *Map:
+		if object.keyType != nil {
+			CheckVarType(*object.keyType, index)
+			CheckVarType(*object.valueType, value)
+		}
+		object.Set(index, value)
+	default:
+		typeError("cannot index value of type '" + object.Type() + "'")
+	}
+}
+
+/*SliceValue copies the elements of a list between two bounds. Nil bounds default to the ends of the list,
+  and negative bounds count from the end */
+func SliceValue(object Object, startObj Object, endObj Object) Object {
+	list, ok := object.(*List)
+	if !ok {
+		typeError("cannot index value of type '" + object.Type() + "'")
+	}
+	length := len(list.Elements)
+	start, end := 0, length
+	if startObj != nil {
+		start = SliceBound(startObj, length)
+	}
+	if endObj != nil {
+		end = SliceBound(endObj, length)
+	}
+	if start > end {
+		indexError(fmt.Sprintf("Slice bounds [%d:%d] are out of order", start, end))
+	}
+	elements := make([]Object, end-start)
+	copy(elements, list.Elements[start:end])
+	return &List{elements, list.elemType}
+}
+
+/*ListIndex resolves an index into a sequence of the passed length, counting negative indexes from the end */
+func ListIndex(index Object, length int) int {
+	given := IntValue(index, "index")
+	idx := given
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx >= length {
+		indexError(fmt.Sprintf("Index %d out of range for list of length %d", given, length))
+	}
+	return idx
+}
+
+/*SliceBound resolves a slice bound, counting negative bounds from the end */
+func SliceBound(bound Object, length int) int {
+	given := IntValue(bound, "slice bound")
+	idx := given
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx > length {
+		indexError(fmt.Sprintf("Slice bound %d out of range for list of length %d", given, length))
+	}
+	return idx
+}
+
+/*IntValue returns the value of an object which must be an integer */
+func IntValue(object Object, name string) int {
+	integer, ok := object.(Integer)
+	if !ok {
+		typeError(name + " must be an int")
+	}
+	return integer.Value
+}
+
+/*GetProperty looks up a property on an object. The properties of a struct instance are its fields and its
+  methods, which are returned bound to the instance */
+func GetProperty(object Object, name string) Object {
+	if instance, ok := object.(*Instance); ok {
+		if value, ok := instance.fields[name]; ok {
+			return value
+		}
+		if method, ok := instance.structType.methods[name]; ok {
+			return method.Bind(instance)
+		}
+		runtimeError("'" + instance.Type() + "' has no field or method '" + name + "'")
+	}
+	if err, ok := object.(*ErrorValue); ok {
+		switch name {
+		case "message":
+			return String{err.Message}
+		case "kind":
+			return String{err.Kind}
+		case "file":
+			if err.Pos.Source != nil {
+				return String{err.Pos.Source.Name}
+			}
+			return String{""}
+		case "line":
+			return Integer{err.Pos.Line}
+		case "column":
+			return Integer{err.Pos.Column}
+		}
+		runtimeError("Error has no property '" + name + "'")
+	}
+	runtimeError("Cannot access property '" + name + "' on '" + object.Type() + "'")
+	return NIL
+}
+
+/*SetProperty assigns to a field of a struct instance, checking the value against the field's declared type */
+func SetProperty(object Object, name string, value Object) {
+	instance, ok := object.(*Instance)
+	if !ok {
+		runtimeError("Cannot set property '" + name + "' on '" + object.Type() + "'")
+	}
+	field, ok := instance.structType.Field(name)
+	if !ok {
+		runtimeError("'" + instance.Type() + "' has no field '" + name + "'")
+	}
+	CheckVarType(field.varType, value)
+	instance.fields[name] = value
+}
@@ -1,24 +1,31 @@
-package main
+package butter
 
 import (
+	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
 )
 
 /*Parser struct contains helpful methods for recursive descvent parsing, as well as keeping track of the
   token list, amnd token currently being processed */
 type Parser struct {
-	tokens  []Token
-	current int
+	tokens        []Token
+	current       int
+	functionDepth int
+	loops         []string
+	errors        []*ParseError
 }
 
 /*NewParser returns a parser object with all of the fields initialized correctly to begin parsing */
 func NewParser(tokens []Token) Parser {
-	return Parser{tokens, 0}
+	return Parser{tokens, 0, 0, nil, nil}
 }
 
 /*Parse parses all of the Tokens into Expression objects and returns those */
 func (p *Parser) Parse() []Stmt {
 	var statements []Stmt
+	p.IgnoreNewlines()
 	for !p.AtEnd() {
 		statements = append(statements, p.Declaration())
 		//Eat newlines before statements
@@ -27,62 +34,238 @@ func (p *Parser) Parse() []Stmt {
 	return statements
 }
 
-func (p *Parser) Declaration() Stmt {
-	if p.Match(INTTYPE, FLOATTYPE, STRINGTYPE, BOOLTYPE) {
+/*Errors returns every syntax error recorded while parsing */
+func (p *Parser) Errors() []*ParseError {
+	return p.errors
+}
+
+/*Declaration parses a single statement. If the statement contains a syntax error, the error is recorded,
+  the parser skips ahead to the start of the next statement and an ErrorStmt is returned in its place */
+func (p *Parser) Declaration() (stmt Stmt) {
+	start := p.current
+	functionDepth := p.functionDepth
+	loops := p.loops
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(*ParseError)
+			if !ok {
+				panic(r)
+			}
+			p.errors = append(p.errors, err)
+			p.functionDepth = functionDepth
+			p.loops = loops
+			p.Synchronize(start)
+			stmt = ErrorStmt{err.Pos, err.Message}
+		}
+	}()
+	if p.Match(typeKeywords...) || p.MatchStructType() || p.Match(VAR, LET) {
 		return p.VarDeclaration()
 	}
+	if p.Match(STRUCT) {
+		return p.StructDeclaration()
+	}
 	if p.Match(LEFTBRACE) {
-		return Block{p.Block()}
+		brace := p.Previous()
+		return Block{brace, p.Block()}
 	}
 	if p.Match(IF) {
 		return p.IfStmt()
 	}
+	if p.Check(IDENTIFIER) && p.Peek().Type == COLON {
+		return p.LabeledLoop()
+	}
 	if p.Match(WHILE) {
-		return p.WhileStmt()
+		return p.WhileStmt(nil)
+	}
+	if p.Match(FOR) {
+		return p.ForStmt(nil)
+	}
+	if p.Match(FN) {
+		return p.FuncDeclaration()
+	}
+	if p.Match(TRY) {
+		return p.TryStmt()
 	}
 	return p.Statement()
 }
 
-func (p *Parser) VarDeclaration() Stmt {
-	varType := p.Previous()
-	if p.Match(IDENTIFIER) {
-		identifier := p.Previous()
-		if p.Match(ASSIGN) {
-			initializer := p.Expression()
-			p.CheckEndline()
-			return VarDeclaration{varType, identifier, initializer}
-		} else {
-			p.CheckEndline()
-			//if there isn't an initializing statement, initialize the value to the zero value for that data type
-			switch varType.Type {
-			case INTTYPE:
-				return VarDeclaration{varType, identifier, Literal{Integer{0}}}
-			case FLOATTYPE:
-				return VarDeclaration{varType, identifier, Literal{Float{0}}}
-			case BOOLTYPE:
-				return VarDeclaration{varType, identifier, Literal{Boolean{false}}}
-			case STRINGTYPE:
-				return VarDeclaration{varType, identifier, Literal{String{""}}}
+/*TryStmt parses a try block followed by a catch block, a finally block or both */
+func (p *Parser) TryStmt() Stmt {
+	keyword := p.Previous()
+	p.Consume(LEFTBRACE, "Expect '{' after try")
+	body := p.Block()
+	p.IgnoreNewlines()
+	var catchName *Token
+	var catchBody []Stmt
+	if p.Match(CATCH) {
+		p.Consume(LEFTGROUP, "Expect '(' after catch")
+		name := p.Consume(IDENTIFIER, "Expect error variable name")
+		catchName = &name
+		p.Consume(RIGHTGROUP, "Expect ')' after error variable name")
+		p.Consume(LEFTBRACE, "Expect '{' after catch")
+		catchBody = p.Block()
+		p.IgnoreNewlines()
+	}
+	var finally []Stmt
+	if p.Match(FINALLY) {
+		p.Consume(LEFTBRACE, "Expect '{' after finally")
+		finally = p.Block()
+	} else if catchName == nil {
+		parseError(p.Current().pos, "Expect 'catch' or 'finally' after try block")
+	}
+	return Try{keyword, body, catchName, catchBody, finally}
+}
+
+/*FuncDeclaration parses a function's optional receiver, name, typed parameter list, optional return type and body */
+func (p *Parser) FuncDeclaration() Stmt {
+	var receiver *Param
+	if p.Match(LEFTGROUP) {
+		receiverType := TypeSpec{token: p.Consume(IDENTIFIER, "Expect struct name for method receiver")}
+		receiverName := p.Consume(IDENTIFIER, "Expect receiver name")
+		receiver = &Param{receiverType, receiverName}
+		p.Consume(RIGHTGROUP, "Expect ')' after method receiver")
+	}
+	name := p.Consume(IDENTIFIER, "Expect function name")
+	p.Consume(LEFTGROUP, "Expect '(' after function name")
+	var params []Param
+	if !p.Check(RIGHTGROUP) {
+		for {
+			if !p.MatchType() {
+				parseError(p.Current().pos, "Expect parameter type")
+			}
+			paramType := p.TypeSpec()
+			paramName := p.Consume(IDENTIFIER, "Expect parameter name")
+			params = append(params, Param{paramType, paramName})
+			if !p.Match(COMMA) {
+				break
 			}
 		}
-	} else {
-		ParseError(p.Previous().line, "expect variable declaration")
 	}
-	//as of now ErrorStmt will never be used, but will eventually catch error
-	return ErrorStmt{"Expect variable declaration"}
+	p.Consume(RIGHTGROUP, "Expect ')' after parameters")
+	var returnType *TypeSpec
+	if p.MatchType() {
+		t := p.TypeSpec()
+		returnType = &t
+	}
+	p.Consume(LEFTBRACE, "Expect '{' before function body")
+	//loops outside of the function cannot be broken out of from inside it
+	loops := p.loops
+	p.loops = nil
+	p.functionDepth++
+	body := p.Block()
+	p.functionDepth--
+	p.loops = loops
+	return FuncDeclaration{receiver, name, params, returnType, body}
+}
+
+/*StructDeclaration parses a struct's name and its typed fields, which are separated by semicolons or newlines */
+func (p *Parser) StructDeclaration() Stmt {
+	keyword := p.Previous()
+	name := p.Consume(IDENTIFIER, "Expect struct name")
+	p.Consume(LEFTBRACE, "Expect '{' after struct name")
+	var fields []Param
+	p.IgnoreNewlines()
+	for !p.Check(RIGHTBRACE) && !p.AtEnd() {
+		if !p.MatchType() {
+			parseError(p.Current().pos, "Expect field type")
+		}
+		fieldType := p.TypeSpec()
+		fieldName := p.Consume(IDENTIFIER, "Expect field name")
+		for _, field := range fields {
+			if field.name.literal == fieldName.literal {
+				parseError(fieldName.pos, "Duplicate field '"+fieldName.literal+"' in struct '"+name.literal+"'")
+			}
+		}
+		fields = append(fields, Param{fieldType, fieldName})
+		if !p.Match(SEMICOLON) && !p.Check(NEWLINE) && !p.Check(RIGHTBRACE) {
+			parseError(p.Current().pos, "Expect ';' or newline after field")
+		}
+		p.IgnoreNewlines()
+	}
+	p.Consume(RIGHTBRACE, "Expect '}' after struct fields")
+	p.CheckEndline()
+	return StructDeclaration{keyword, name, fields}
+}
+
+func (p *Parser) VarDeclaration() Stmt {
+	stmt := p.VarDefinition()
+	p.CheckEndline()
+	return stmt
+}
+
+/*VarDefinition parses a variable's name and optional initializer after its type, leaving the end of the line alone.
+  Variables declared with var or let take their type from their initializer */
+func (p *Parser) VarDefinition() Stmt {
+	varType := p.TypeSpec()
+	identifier := p.Consume(IDENTIFIER, "expect variable declaration")
+	//without an initializer the variable starts at the zero value for its type
+	var initializer Expr
+	if p.Match(ASSIGN) {
+		p.IgnoreNewlines()
+		initializer = p.Expression()
+	} else if varType.token.Type == IDENTIFIER {
+		parseError(identifier.pos, "Variable of struct type '"+varType.String()+"' must be initialized")
+	} else if varType.Inferred() {
+		parseError(identifier.pos, "Variable declared with '"+strings.ToLower(varType.token.Type.String())+"' must be initialized")
+	}
+	return VarDeclaration{varType, identifier, initializer}
+}
+
+/*TypeSpec parses the rest of a type after its keyword, such as the element type of list<int> */
+func (p *Parser) TypeSpec() TypeSpec {
+	spec := TypeSpec{token: p.Previous()}
+	if spec.token.Type == LISTTYPE {
+		p.Consume(LESS, "Expect '<' after list")
+		if !p.MatchType() {
+			parseError(p.Current().pos, "Expect element type")
+		}
+		spec.params = []TypeSpec{p.TypeSpec()}
+		p.Consume(GREATER, "Expect '>' after element type")
+	}
+	if spec.token.Type == MAPTYPE {
+		p.Consume(LESS, "Expect '<' after map")
+		if !p.Match(keyTypes...) {
+			parseError(p.Current().pos, "Expect map key type of int, float, string or bool")
+		}
+		keyType := p.TypeSpec()
+		p.Consume(COMMA, "Expect ',' after map key type")
+		if !p.MatchType() {
+			parseError(p.Current().pos, "Expect map value type")
+		}
+		spec.params = []TypeSpec{keyType, p.TypeSpec()}
+		p.Consume(GREATER, "Expect '>' after map value type")
+	}
+	return spec
+}
+
+/*MatchType consumes the first token of a type, which is either a type keyword or the name of a struct */
+func (p *Parser) MatchType() bool {
+	return p.Match(typeKeywords...) || p.Match(IDENTIFIER)
+}
+
+/*MatchStructType consumes a struct name which begins a declaration, such as Point in Point p := ... */
+func (p *Parser) MatchStructType() bool {
+	if p.Check(IDENTIFIER) && p.Peek().Type == IDENTIFIER {
+		p.Advance()
+		return true
+	}
+	return false
 }
 
 func (p *Parser) Block() []Stmt {
 	p.Consume(NEWLINE, "Expect newline after block")
 	var stmts []Stmt
+	p.IgnoreNewlines()
 	for !p.Check(RIGHTBRACE) && !p.AtEnd() {
 		stmts = append(stmts, p.Declaration())
+		p.IgnoreNewlines()
 	}
 	p.Consume(RIGHTBRACE, "Expect '}' after block.")
 	return stmts
 }
 
 func (p *Parser) IfStmt() Stmt {
+	keyword := p.Previous()
 	condition := p.Expression()
 	ifTrue := p.Declaration()
 	p.IgnoreNewlines()
@@ -90,25 +273,154 @@ func (p *Parser) IfStmt() Stmt {
 	if p.Match(ELSE) {
 		ifFalse = p.Declaration()
 	}
-	return If{condition, ifTrue, ifFalse}
+	return If{keyword, condition, ifTrue, ifFalse}
 }
 
-func (p *Parser) WhileStmt() Stmt {
+/*LabeledLoop parses a label followed by the loop it names, which break and continue can refer to */
+func (p *Parser) LabeledLoop() Stmt {
+	label := p.Consume(IDENTIFIER, "Expect loop label")
+	p.Consume(COLON, "Expect ':' after loop label")
+	for _, enclosing := range p.loops {
+		if enclosing == label.literal {
+			parseError(label.pos, "Loop label '"+label.literal+"' is already in use")
+		}
+	}
+	if p.Match(WHILE) {
+		return p.WhileStmt(&label)
+	}
+	if p.Match(FOR) {
+		return p.ForStmt(&label)
+	}
+	parseError(p.Current().pos, "Expect loop after label")
+	return nil
+}
+
+func (p *Parser) WhileStmt(label *Token) Stmt {
+	keyword := p.Previous()
 	condition := p.Expression()
-	body := p.Declaration()
-	return While{condition, body}
+	body := p.LoopBody(label, p.Declaration)
+	return While{keyword, labelName(label), condition, body}
+}
+
+/*ForStmt parses a C style for loop, any of whose initializer, condition and increment may be left out */
+func (p *Parser) ForStmt(label *Token) Stmt {
+	keyword := p.Previous()
+	if p.Check(IDENTIFIER) && (p.Peek().Type == IN || p.Peek().Type == COMMA) {
+		return p.ForInStmt(keyword, label)
+	}
+	var initializer Stmt
+	if p.Match(typeKeywords...) || p.MatchStructType() || p.Match(VAR, LET) {
+		initializer = p.VarDefinition()
+	} else if !p.Check(SEMICOLON) {
+		initializer = ExprStmt{p.Expression()}
+	}
+	p.Consume(SEMICOLON, "Expect ';' after loop initializer")
+	var condition Expr
+	if !p.Check(SEMICOLON) {
+		condition = p.Expression()
+	}
+	p.Consume(SEMICOLON, "Expect ';' after loop condition")
+	var increment Expr
+	if !p.Check(LEFTBRACE) {
+		increment = p.Expression()
+	}
+	body := p.LoopBody(label, func() Stmt {
+		brace := p.Consume(LEFTBRACE, "Expect '{' after for clauses")
+		return Block{brace, p.Block()}
+	})
+	return For{keyword, labelName(label), initializer, condition, increment, body}
+}
+
+/*ForInStmt parses a loop over the elements of a list or the entries of a map. A second name binds the
+  element's index, or the entry's value */
+func (p *Parser) ForInStmt(keyword Token, label *Token) Stmt {
+	names := []Token{p.Consume(IDENTIFIER, "Expect loop variable")}
+	if p.Match(COMMA) {
+		names = append(names, p.Consume(IDENTIFIER, "Expect second loop variable after ','"))
+	}
+	p.Consume(IN, "Expect 'in' after loop variables")
+	iterable := p.Expression()
+	body := p.LoopBody(label, func() Stmt {
+		brace := p.Consume(LEFTBRACE, "Expect '{' after for clause")
+		return Block{brace, p.Block()}
+	})
+	return ForIn{keyword, labelName(label), names, iterable, body}
+}
+
+/*LoopBody parses the body of a loop, letting break and continue statements within it refer to the loop */
+func (p *Parser) LoopBody(label *Token, body func() Stmt) Stmt {
+	p.loops = append(p.loops, labelName(label))
+	defer func() { p.loops = p.loops[:len(p.loops)-1] }()
+	return body()
+}
+
+/*LoopControl parses a break or continue statement, checking that it is within the loop it refers to */
+func (p *Parser) LoopControl() Stmt {
+	keyword := p.Previous()
+	var label string
+	if p.Match(IDENTIFIER) {
+		label = p.Previous().literal
+	}
+	if len(p.loops) == 0 {
+		parseError(keyword.pos, "Cannot use '"+strings.ToLower(keyword.Type.String())+"' outside of a loop")
+	}
+	if label != "" {
+		found := false
+		for _, enclosing := range p.loops {
+			found = found || enclosing == label
+		}
+		if !found {
+			parseError(p.Previous().pos, "No enclosing loop labeled '"+label+"'")
+		}
+	}
+	p.CheckEndline()
+	return LoopControl{keyword, label}
+}
+
+func labelName(label *Token) string {
+	if label == nil {
+		return ""
+	}
+	return label.literal
 }
 
 /*Line Parses an expression, then eats any trailing whitespace */
 func (p *Parser) Statement() Stmt {
 	if p.Match(PRINT) {
+		keyword := p.Previous()
 		expr := p.Expression()
 		p.CheckEndline()
-		return Print{expr}
+		return Print{keyword, expr}
+	}
+	if p.Match(RETURN) {
+		return p.ReturnStmt()
+	}
+	if p.Match(BREAK, CONTINUE) {
+		return p.LoopControl()
+	}
+	if p.Match(THROW) {
+		keyword := p.Previous()
+		value := p.Expression()
+		p.CheckEndline()
+		return Throw{keyword, value}
 	}
 	return p.ExpressionStatement()
 }
 
+/*ReturnStmt parses a return statement with an optional value */
+func (p *Parser) ReturnStmt() Stmt {
+	keyword := p.Previous()
+	if p.functionDepth == 0 {
+		parseError(keyword.pos, "Cannot return from top-level code")
+	}
+	var value Expr
+	if !p.Check(NEWLINE) && !p.Check(RIGHTBRACE) && !p.AtEnd() {
+		value = p.Expression()
+	}
+	p.CheckEndline()
+	return Return{keyword, value}
+}
+
 func (p *Parser) ExpressionStatement() Stmt {
 	exprStmt := ExprStmt{p.Expression()}
 	p.CheckEndline()
@@ -126,11 +438,16 @@ func (p *Parser) Assignment() Expr {
 	expr := p.Or()
 
 	if p.Match(ASSIGN) {
+		p.IgnoreNewlines()
 		value := p.Assignment()
 		if e, ok := expr.(Variable); ok {
-			return Assign{e.identifier, value}
+			return Assign{e.identifier, value, e.binding}
+		} else if e, ok := expr.(Index); ok {
+			return SetIndex{e.object, e.bracket, e.index, value}
+		} else if e, ok := expr.(Get); ok {
+			return Set{e.object, e.name, value}
 		} else {
-			ParseError(p.Previous().line, "Invalid assignment target")
+			parseError(p.Previous().pos, "Invalid assignment target")
 		}
 	}
 
@@ -143,8 +460,9 @@ func (p *Parser) Or() Expr {
 
 	for p.Match(OR) {
 		operator := p.Previous()
+		p.IgnoreNewlines()
 		right := p.And()
-		expr = Binary{expr, right, operator}
+		expr = Logical{expr, right, operator}
 	}
 
 	return expr
@@ -156,8 +474,9 @@ func (p *Parser) And() Expr {
 
 	for p.Match(AND) {
 		operator := p.Previous()
+		p.IgnoreNewlines()
 		right := p.Equality()
-		expr = Binary{expr, right, operator}
+		expr = Logical{expr, right, operator}
 	}
 
 	return expr
@@ -169,6 +488,7 @@ func (p *Parser) Equality() Expr {
 
 	for p.Match(EQUALEQUAL, BANGEQUAL) {
 		operator := p.Previous()
+		p.IgnoreNewlines()
 		right := p.Comparison()
 		expr = Binary{expr, right, operator}
 	}
@@ -178,22 +498,11 @@ func (p *Parser) Equality() Expr {
 
 /*Comparison parses both sides of an expression, then connects them with a comparison operator (if applicable) */
 func (p *Parser) Comparison() Expr {
-	expr := p.Exponent()
-
-	for p.Match(GREATER, GREATEREQUAL, LESS, LESSEQUAL) {
-		operator := p.Previous()
-		right := p.Exponent()
-		expr = Binary{expr, right, operator}
-	}
-
-	return expr
-}
-
-func (p *Parser) Exponent() Expr {
 	expr := p.Addition()
 
-	for p.Match(EXP) {
+	for p.Match(GREATER, GREATEREQUAL, LESS, LESSEQUAL, IN) {
 		operator := p.Previous()
+		p.IgnoreNewlines()
 		right := p.Addition()
 		expr = Binary{expr, right, operator}
 	}
@@ -207,6 +516,7 @@ func (p *Parser) Addition() Expr {
 
 	for p.Match(MINUS, PLUS) {
 		operator := p.Previous()
+		p.IgnoreNewlines()
 		right := p.Multiplication()
 		expr = Binary{expr, right, operator}
 	}
@@ -220,6 +530,7 @@ func (p *Parser) Multiplication() Expr {
 
 	for p.Match(MULT, DIV, MOD) {
 		operator := p.Previous()
+		p.IgnoreNewlines()
 		right := p.Unary()
 		expr = Binary{expr, right, operator}
 	}
@@ -227,55 +538,264 @@ func (p *Parser) Multiplication() Expr {
 	return expr
 }
 
+/*Unary parses any number of prefix operators applied to an exponent expression, so -x ** 2 is -(x ** 2) */
 func (p *Parser) Unary() Expr {
-	for p.Match(BANG, MINUS) {
+	if p.Check(MINUS) && p.Peek().Type == INT && p.tokens[p.current+2].Type != EXP {
+		//the lowest int only fits once it is negated, so it is read as a single literal
+		if integer, ok := negatedIntLiteral(p.Peek().literal); ok {
+			operator := p.Current()
+			p.Advance()
+			p.Advance()
+			return Literal{operator.pos, Integer{integer}}
+		}
+	}
+	if p.Match(BANG, MINUS) {
 		operator := p.Previous()
-		right := p.Literal()
+		right := p.Unary()
 		return Unary{right, operator}
 	}
 
-	return p.Literal()
+	return p.Exponent()
+}
+
+/*Exponent parses a right associative exponent, binding tighter than the unary operators on its left but
+  allowing them on its right, so 2 ** 3 ** 2 is 2 ** (3 ** 2) and 2 ** -1 is 2 ** (-1) */
+func (p *Parser) Exponent() Expr {
+	expr := p.Call()
+
+	if p.Match(EXP) {
+		operator := p.Previous()
+		p.IgnoreNewlines()
+		right := p.Unary()
+		expr = Binary{expr, right, operator}
+	}
+
+	return expr
+}
+
+/*Call parses a primary expression followed by any number of argument lists, property accesses and indexes */
+func (p *Parser) Call() Expr {
+	expr := p.Literal()
+
+	for {
+		if p.Match(LEFTGROUP) {
+			expr = p.FinishCall(expr)
+		} else if p.Match(DOT) {
+			name := p.Consume(IDENTIFIER, "Expect property name after '.'")
+			expr = Get{expr, name}
+		} else if p.Match(LEFTBRACKET) {
+			expr = p.FinishIndex(expr)
+		} else {
+			break
+		}
+	}
+
+	return expr
+}
+
+/*FinishIndex parses an index, or a slice with optional bounds, after the opening bracket */
+func (p *Parser) FinishIndex(object Expr) Expr {
+	bracket := p.Previous()
+	var start, end Expr
+	if !p.Check(COLON) {
+		start = p.Expression()
+	}
+	if p.Match(COLON) {
+		if !p.Check(RIGHTBRACKET) {
+			end = p.Expression()
+		}
+		p.Consume(RIGHTBRACKET, "Expect ']' after slice")
+		return Slice{object, bracket, start, end}
+	}
+	p.Consume(RIGHTBRACKET, "Expect ']' after index")
+	return Index{object, bracket, start}
+}
+
+/*ListLiteral parses the comma separated elements of a list after the opening bracket. The elements may
+  span several lines and be followed by a trailing comma */
+func (p *Parser) ListLiteral() Expr {
+	bracket := p.Previous()
+	var elements []Expr
+	p.IgnoreNewlines()
+	for !p.Check(RIGHTBRACKET) {
+		elements = append(elements, p.Expression())
+		p.IgnoreNewlines()
+		if !p.Match(COMMA) {
+			break
+		}
+		p.IgnoreNewlines()
+	}
+	p.Consume(RIGHTBRACKET, "Expect ']' after list elements")
+	return ListLiteral{bracket, elements}
+}
+
+/*MapLiteral parses the comma separated key: value entries of a map after the opening brace. The entries may
+  span several lines and be followed by a trailing comma */
+func (p *Parser) MapLiteral() Expr {
+	brace := p.Previous()
+	var keys, values []Expr
+	p.IgnoreNewlines()
+	for !p.Check(RIGHTBRACE) {
+		keys = append(keys, p.Expression())
+		p.Consume(COLON, "Expect ':' after map key")
+		values = append(values, p.Expression())
+		p.IgnoreNewlines()
+		if !p.Match(COMMA) {
+			break
+		}
+		p.IgnoreNewlines()
+	}
+	p.Consume(RIGHTBRACE, "Expect '}' after map entries")
+	return MapLiteral{brace, keys, values}
+}
+
+/*FinishCall parses the comma separated arguments of a call after the opening parenthesis */
+func (p *Parser) FinishCall(callee Expr) Expr {
+	var args []Expr
+	if !p.Check(RIGHTGROUP) {
+		for {
+			args = append(args, p.Expression())
+			if !p.Match(COMMA) {
+				break
+			}
+		}
+	}
+	paren := p.Consume(RIGHTGROUP, "Expect ')' after arguments")
+	return Call{callee, paren, args}
 }
 
 /*Literal returns an object of the type of the token passes, with a value parsed from the Token literal */
 func (p *Parser) Literal() Expr {
 	if p.Match(INT) {
 		prev := p.Previous()
-		integer, err := strconv.Atoi(prev.literal)
-		CheckError(err)
-		return Literal{Integer{integer}}
+		integer, err := ParseIntLiteral(prev.literal)
+		if err != nil {
+			parseError(prev.pos, err.Error())
+		}
+		return Literal{prev.pos, Integer{integer}}
 	}
 	if p.Match(FLOAT) {
 		prev := p.Previous()
-		float, err := strconv.ParseFloat(prev.literal, 64)
+		float, err := ParseFloatLiteral(prev.literal)
 		if err != nil {
-			ParseError(prev.line, "Unable to parse float")
+			parseError(prev.pos, err.Error())
 		}
-		return Literal{Float{float}}
+		return Literal{prev.pos, Float{float}}
 	}
 	if p.Match(STRING) {
 		prev := p.Previous()
-		return Literal{String{prev.literal}}
+		return Literal{prev.pos, String{prev.literal}}
+	}
+	if p.Match(INTERPOLATION) {
+		return p.Interpolation(p.Previous())
+	}
+	if p.Match(LEFTBRACKET) {
+		return p.ListLiteral()
+	}
+	if p.Match(LEFTBRACE) {
+		return p.MapLiteral()
 	}
 	if p.Match(TRUE) {
-		return Literal{Boolean{true}}
+		return Literal{p.Previous().pos, Boolean{true}}
 	}
 	if p.Match(FALSE) {
-		return Literal{Boolean{false}}
+		return Literal{p.Previous().pos, Boolean{false}}
 	}
 	if p.Match(LEFTGROUP) {
+		paren := p.Previous()
 		expr := p.Expression()
 		p.Consume(RIGHTGROUP, "Expect ')' after expression")
-		return Grouping{expr}
+		return Grouping{paren, expr}
 	}
 	if p.Match(IDENTIFIER) {
 		prev := p.Previous()
-		return Variable{prev}
+		return Variable{prev, &Binding{depth: -1}}
 	}
-	ParseError(p.Current().line, "Expect expression, received->"+p.Current().Type.String()+" "+p.Current().literal)
+	parseError(p.Current().pos, "Expect expression, received->"+p.Current().Type.String()+" "+p.Current().literal)
 	return nil
 }
 
+var (
+	decimalLiteral = regexp.MustCompile(`^[0-9]+(_[0-9]+)*$`)
+	floatLiteral   = regexp.MustCompile(`^[0-9]+(_[0-9]+)*(\.([0-9]+(_[0-9]+)*)?)?([eE][+-]?[0-9]+(_[0-9]+)*)?$`)
+)
+
+/*ParseIntLiteral converts the text of an INT token to an int, reporting literals which are malformed or
+  too large to fit */
+func ParseIntLiteral(literal string) (int, error) {
+	return parseIntLiteral(literal, "")
+}
+
+/*negatedIntLiteral returns the negated value of an int literal which only fits in an int once negated, like
+  9223372036854775808 */
+func negatedIntLiteral(literal string) (int, bool) {
+	if _, err := parseIntLiteral(literal, ""); err == nil {
+		return 0, false
+	}
+	integer, err := parseIntLiteral(literal, "-")
+	return integer, err == nil
+}
+
+/*parseIntLiteral converts the text of an INT token to an int, with sign placed in front of it */
+func parseIntLiteral(literal string, sign string) (int, error) {
+	text, base := literal, 0
+	if !strings.HasPrefix(literal, "0") || len(literal) == 1 || IsNum(literal[1]) || literal[1] == '_' {
+		//without a prefix the literal is decimal, even with leading zeros
+		if !decimalLiteral.MatchString(literal) {
+			return 0, fmt.Errorf("Invalid integer literal '%s'", literal)
+		}
+		text, base = strings.Replace(literal, "_", "", -1), 10
+	}
+	integer, err := strconv.ParseInt(sign+text, base, strconv.IntSize)
+	if err != nil {
+		if err.(*strconv.NumError).Err == strconv.ErrRange {
+			return 0, fmt.Errorf("Integer literal '%s' overflows int", literal)
+		}
+		return 0, fmt.Errorf("Invalid integer literal '%s'", literal)
+	}
+	return int(integer), nil
+}
+
+/*ParseFloatLiteral converts the text of a FLOAT token to a float64, reporting literals which are malformed or
+  too large to fit */
+func ParseFloatLiteral(literal string) (float64, error) {
+	if !floatLiteral.MatchString(literal) {
+		return 0, fmt.Errorf("Invalid float literal '%s'", literal)
+	}
+	float, err := strconv.ParseFloat(strings.Replace(literal, "_", "", -1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("Float literal '%s' overflows float", literal)
+	}
+	return float, nil
+}
+
+/*formatSpec matches the format specifiers allowed after a ':' in an interpolated expression */
+var formatSpec = regexp.MustCompile(`^[-+ 0#]*[0-9]*(\.[0-9]+)?[dxXobfeEgGsq]?$`)
+
+/*Interpolation parses each expression embedded in an interpolated string with its own parser */
+func (p *Parser) Interpolation(token Token) Expr {
+	var parts []Expr
+	var specs []string
+	for _, part := range token.parts {
+		if part.tokens == nil {
+			parts = append(parts, Literal{token.pos, String{part.text}})
+			specs = append(specs, "")
+			continue
+		}
+		parser := NewParser(part.tokens)
+		expr := parser.Expression()
+		if !parser.AtEnd() {
+			parseError(parser.Current().pos, "Expect '}' after interpolated expression")
+		}
+		if !formatSpec.MatchString(part.spec) {
+			parseError(part.specPos, "Invalid format specifier '"+part.spec+"'")
+		}
+		parts = append(parts, expr)
+		specs = append(specs, part.spec)
+	}
+	return Interpolation{token, parts, specs}
+}
+
 /*AtEnd checks if the current token is the last one in the file and returns true if so, otherwise false */
 func (p *Parser) AtEnd() bool {
 	return p.Current().Type == EOF
@@ -293,6 +813,14 @@ func (p *Parser) Current() Token {
 	return p.tokens[p.current]
 }
 
+/*Peek returns the token after the current one */
+func (p *Parser) Peek() Token {
+	if p.AtEnd() {
+		return p.Current()
+	}
+	return p.tokens[p.current+1]
+}
+
 /*Previous returns the previous token under consideration */
 func (p *Parser) Previous() Token {
 	return p.tokens[p.current-1]
@@ -311,13 +839,13 @@ func (p *Parser) Match(ts ...TokenType) bool {
 }
 
 /*Consume advances if the next token matches a specific tokentype, otherwise gives a ParseError */
-func (p *Parser) Consume(t TokenType, message string) {
+func (p *Parser) Consume(t TokenType, message string) Token {
 	if p.Check(t) {
 		p.Advance()
 	} else {
-		ParseError(p.Current().line, message)
+		parseError(p.Current().pos, message)
 	}
-
+	return p.Previous()
 }
 
 /*Check returns true if the current token matches a passed tokentype, otherwise false */
@@ -328,6 +856,46 @@ func (p *Parser) Check(t TokenType) bool {
 	return p.Current().Type == t
 }
 
+/*Synchronize discards tokens until it reaches the end of the line, a closing brace or a keyword which
+  begins a new statement. If the statement which failed, starting at the token start, has opened a block
+  then the whole block is discarded, so its body isn't parsed as statements of their own */
+func (p *Parser) Synchronize(start int) {
+	depth := 0
+	for _, token := range p.tokens[start:p.current] {
+		depth += braceDepth(token.Type)
+	}
+	if depth < 0 {
+		depth = 0
+	}
+	from := p.current
+	for !p.AtEnd() {
+		if depth == 0 {
+			if p.Match(NEWLINE) {
+				return
+			}
+			if p.current != from {
+				switch p.Current().Type {
+				case RIGHTBRACE, FN, RETURN, IF, WHILE, FOR, BREAK, CONTINUE, PRINT, TRY, THROW, STRUCT, VAR, LET, INTTYPE, FLOATTYPE, BOOLTYPE, STRINGTYPE, LISTTYPE, MAPTYPE:
+					return
+				}
+			}
+		}
+		depth += braceDepth(p.Current().Type)
+		p.Advance()
+	}
+}
+
+/*braceDepth returns how a token changes the number of open braces */
+func braceDepth(tokenType TokenType) int {
+	switch tokenType {
+	case LEFTBRACE:
+		return 1
+	case RIGHTBRACE:
+		return -1
+	}
+	return 0
+}
+
 func (p *Parser) IgnoreNewlines() {
 	for !p.AtEnd() && p.Match(NEWLINE) {
 	}
@@ -337,6 +905,6 @@ func (p *Parser) CheckEndline() bool {
 	if p.Match(NEWLINE, EOF) {
 		return true
 	}
-	ParseError(p.Current().line, "Expected new line after statement")
+	parseError(p.Current().pos, "Expected new line after statement")
 	return false
 }
@@ -0,0 +1,279 @@
+package butter
+
+import (
+	"strings"
+	"testing"
+)
+
+/*parseExpression parses a single expression, failing the test on any syntax error */
+func parseExpression(t *testing.T, src string) (expr Expr) {
+	t.Helper()
+	tokenizer := NewTokenizer(NewSource("test", src))
+	tokens := tokenizer.Tokenize()
+	if errs := tokenizer.Errors(); len(errs) > 0 {
+		t.Fatalf("tokenizing %q: %v", src, ParseErrors(errs))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("parsing %q: %v", src, r)
+		}
+	}()
+	parser := NewParser(tokens)
+	expr = parser.Expression()
+	if !parser.AtEnd() {
+		t.Fatalf("parsing %q: unexpected %s", src, parser.Current())
+	}
+	return expr
+}
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		// assignment is the loosest and right associative
+		{"a := b := 1 or 2", "(:= a (:= b (or 1 2)))"},
+		// or / and
+		{"a or b and c", "(or a (and b c))"},
+		{"a and b or c", "(or (and a b) c)"},
+		{"a or b or c", "(or (or a b) c)"},
+		{"a and b == c", "(and a (== b c))"},
+		// equality / comparison
+		{"a == b != c", "(!= (== a b) c)"},
+		{"a == b < c", "(== a (< b c))"},
+		{"a < b + c", "(< a (+ b c))"},
+		{"a >= b - c", "(>= a (- b c))"},
+		// addition / multiplication
+		{"1 + 2 - 3", "(- (+ 1 2) 3)"},
+		{"1 + 2 * 3", "(+ 1 (* 2 3))"},
+		{"1 * 2 / 3 % 4", "(% (/ (* 1 2) 3) 4)"},
+		{"1 * -2", "(* 1 (- 2))"},
+		// unary
+		{"!!flag", "(! (! flag))"},
+		{"- -3", "(- (- 3))"},
+		{"--3", "(- (- 3))"},
+		{"-a * b", "(* (- a) b)"},
+		{"!a and b", "(and (! a) b)"},
+		// exponent binds tighter than unary and multiplication, and is right associative
+		{"2 + 3 ** 2", "(+ 2 (** 3 2))"},
+		{"2 * 3 ** 2", "(* 2 (** 3 2))"},
+		{"-x ** 2", "(- (** x 2))"},
+		{"2 ** 3 ** 2", "(** 2 (** 3 2))"},
+		{"2 ** -1", "(** 2 (- 1))"},
+		{"(2 + 3) ** 2", "(** (group (+ 2 3)) 2)"},
+		// calls and property access bind tightest
+		{"-f(x) ** 2", "(- (** (call f x) 2))"},
+		{"e.line ** 2", "(** (. e line) 2)"},
+		{"f(1)(2)", "(call (call f 1) 2)"},
+		{"-xs[0] ** 2", "(- (** (index xs 0) 2))"},
+		{"xs[1:] + [1, 2]", "(+ (slice xs 1 (nil)) (list 1 2))"},
+		{"xs[i] := 1 + 2", "(:= (index xs i) (+ 1 2))"},
+		{"k in m == ok", "(== (in k m) ok)"},
+		{"p.x := q.y := 1 + 2", "(:= (. p x) (:= (. q y) (+ 1 2)))"},
+		{"a.b.c := 1", "(:= (. (. a b) c) 1)"},
+		{"a + 1 in xs", "(in (+ a 1) xs)"},
+		{"{\"a\": 1, 2: b}[k]", "(index (map \"a\" 1 2 b) k)"},
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			if got := ExprString(parseExpression(t, test.src)); got != test.want {
+				t.Errorf("%q parsed as %s, want %s", test.src, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrecedenceEvaluation(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"2 + 3 ** 2", "11"},
+		{"2 ** 3 ** 2", "512"},
+		{"-2 ** 2", "-4"},
+		{"(-2) ** 2", "4"},
+		{"2 * 3 ** 2", "18"},
+		{"10 - 4 - 3", "3"},
+		{"- -3", "3"},
+		{"!!true", "TRUE"},
+		{"!true or true", "TRUE"},
+		{"1 + 2 < 4 == true", "TRUE"},
+		{"2.0 ** -1", "0.5"},
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			result, err := New().Eval(test.src)
+			if err != nil {
+				t.Fatalf("evaluating %q: %v", test.src, err)
+			}
+			if got := Stringify(result); got != test.want {
+				t.Errorf("%q = %s, want %s", test.src, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"print 1\n", false},
+		{"if x > 1 {\n", true},
+		{"fn f() {\n  print 1\n\n", true},
+		{"print 1 +\n", true},
+		{"int x :=\n", true},
+		{"f(1,\n", true},
+		{"print \"\"\"first line\n", true},
+		{"/* comment\n", true},
+		{"print \"unclosed\n", false},
+		{"print )\n", false},
+		{"{\n}\n}\n", false},
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			if got := Incomplete(test.src); got != test.want {
+				t.Errorf("Incomplete(%q) = %v, want %v", test.src, got, test.want)
+			}
+		})
+	}
+}
+
+func TestContinuedExpressions(t *testing.T) {
+	result, err := New().Eval("int x :=\n  1 +\n  2 *\n  3\nx == 7 and\n  x > 0")
+	if err != nil {
+		t.Fatalf("evaluating: %v", err)
+	}
+	if got := Stringify(result); got != "TRUE" {
+		t.Errorf("got %s, want TRUE", got)
+	}
+}
+
+func TestStmtString(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"int x := 1 + 2", "(var int x (+ 1 2))"},
+		{"print \"a${x:5d}b${\"c\"}\"", "(print (interpolate \"a\" (format x \"5d\") \"b\" \"c\"))"},
+		{"list<int> xs", "(var list<int> xs)"},
+		{"if x {\n  print 1\n} else print 2", "(if x (block (print 1)) (print 2))"},
+		{"outer: while true {\n  break outer\n}", "(label outer (while TRUE (block (break outer))))"},
+		{"for ; i < 3; {\n}", "(for (nil) (< i 3) (nil) (block))"},
+		{"for k, v in m {\n  continue\n}", "(for-in (k v) m (block (continue)))"},
+		{"fn add(int a, int b) int {\n  return a + b\n}", "(fn add (int a int b) int (block (return (+ a b))))"},
+		{"struct P { int a; float b }", "(struct P int a float b)"},
+		{"fn (P p) get() {\n  return\n}", "(fn (P p) get () (block (return)))"},
+		{"try {\n  throw \"x\"\n} catch (e) {\n  e\n}", "(try (block (throw \"x\")) (catch e e))"},
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			stmts, err := Parse(NewSource("test", test.src))
+			if err != nil {
+				t.Fatalf("parsing %q: %v", test.src, err)
+			}
+			if got := StmtString(stmts[0]); got != test.want {
+				t.Errorf("%q printed as %s, want %s", test.src, got, test.want)
+			}
+		})
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"if x > {\n print 1\n}\nwhile (x {\n print 2\n}\n", []string{
+			"PARSE_ERROR [test:2:2]: Expect expression, received->PRINT ",
+			"PARSE_ERROR [test:4:10]: Expect ')' after expression",
+		}},
+		{"if x == {\n print 1\n} else {\n print 2\n}\nprint 3 +\n", []string{
+			"PARSE_ERROR [test:2:2]: Expect expression, received->PRINT ",
+			"PARSE_ERROR [test:7:1]: Expect expression, received->EOF ",
+		}},
+		{"fn f() {\n print (\n print 4\n}\nprint )\n", []string{
+			"PARSE_ERROR [test:2:9]: Expect expression, received->\\n ",
+			"PARSE_ERROR [test:5:7]: Expect expression, received->) ",
+		}},
+		{"struct P { int }\nfor int i := 0; i < ; i := i + 1 {\n  print i\n}\nint := 2\nprint 5\n", []string{
+			"PARSE_ERROR [test:1:16]: Expect field name",
+			"PARSE_ERROR [test:2:21]: Expect expression, received->; ",
+			"PARSE_ERROR [test:5:5]: expect variable declaration",
+		}},
+		{"var x\nlet y\n", []string{
+			"PARSE_ERROR [test:1:5]: Variable declared with 'var' must be initialized",
+			"PARSE_ERROR [test:2:5]: Variable declared with 'let' must be initialized",
+		}},
+		{"print \"${1 +}\"\nprint 6 6\n", []string{
+			"PARSE_ERROR [test:1:13]: Expect expression, received->EOF ",
+			"PARSE_ERROR [test:2:9]: Expected new line after statement",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			_, err := Parse(NewSource("test", test.src))
+			errs, _ := err.(ParseErrors)
+			var got []string
+			for _, parseErr := range errs {
+				got = append(got, parseErr.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("parsing %q reported\n%s\nwant\n%s", test.src, strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestLoopControlErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"break", "PARSE_ERROR [test:1:1]: Cannot use 'break' outside of a loop"},
+		{"continue", "PARSE_ERROR [test:1:1]: Cannot use 'continue' outside of a loop"},
+		{"while true {\n  break nope\n}", "PARSE_ERROR [test:2:9]: No enclosing loop labeled 'nope'"},
+		{"outer: while true {\n  fn f() {\n    break outer\n  }\n  break\n}", "PARSE_ERROR [test:3:5]: Cannot use 'break' outside of a loop"},
+		{"while true {\n  fn f() {\n    continue\n  }\n  break\n}", "PARSE_ERROR [test:3:5]: Cannot use 'continue' outside of a loop"},
+		{"a: print 1", "PARSE_ERROR [test:1:4]: Expect loop after label"},
+		{"a: while false {\n  a: while false {\n  }\n}", "PARSE_ERROR [test:2:3]: Loop label 'a' is already in use"},
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			_, err := Parse(NewSource("test", test.src))
+			if got := errorString(err); got != test.err {
+				t.Errorf("parsing %q reported %q, want %q", test.src, got, test.err)
+			}
+		})
+	}
+}
+
+func TestErrorStatements(t *testing.T) {
+	src := "print 1\nprint (\n{\n  print )\n}\nprint 2\n"
+	tokenizer := NewTokenizer(NewSource("test", src))
+	parser := NewParser(tokenizer.Tokenize())
+	var got []string
+	for _, stmt := range parser.Parse() {
+		got = append(got, StmtString(stmt))
+	}
+	want := `(print 1) (error "Expect expression, received->\\n ") (block (error "Expect expression, received->) ")) (print 2)`
+	if strings.Join(got, " ") != want {
+		t.Errorf("parsing %q gave %s, want %s", src, strings.Join(got, " "), want)
+	}
+	if len(parser.Errors()) != 2 {
+		t.Errorf("parsing %q reported %v, want 2 errors", src, parser.Errors())
+	}
+}
+
+func TestSyntaxErrorsStopTheProgram(t *testing.T) {
+	src := "print 1\nprint (\nprint 2 +\n"
+	for _, bytecode := range []bool{false, true} {
+		output, err := runConformance(src, bytecode)
+		if output != "" {
+			t.Errorf("printed %q before the errors were reported", output)
+		}
+		if errs, ok := err.(ParseErrors); !ok || len(errs) != 2 {
+			t.Errorf("failed with %v, want both syntax errors", err)
+		}
+	}
+}
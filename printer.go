@@ -0,0 +1,204 @@
+package butter
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*ExprString returns a fully parenthesized, lisp-like representation of an expression tree, so 2 + 3 * 4
+  becomes (+ 2 (* 3 4)). It is used to inspect how source has been parsed */
+func ExprString(e Expr) string {
+	switch e := e.(type) {
+	case Literal:
+		if str, ok := e.obj.(String); ok {
+			return strconv.Quote(str.Value)
+		}
+		return Stringify(e.obj)
+	case Variable:
+		return e.identifier.literal
+	case Assign:
+		return parenthesize(":=", Variable{e.identifier, e.binding}, e.initializer)
+	case Binary:
+		return parenthesize(operatorString(e.operator), e.left, e.right)
+	case Logical:
+		return parenthesize(operatorString(e.operator), e.left, e.right)
+	case Unary:
+		return parenthesize(operatorString(e.operator), e.right)
+	case Grouping:
+		return parenthesize("group", e.expr)
+	case Call:
+		return parenthesize("call", append([]Expr{e.callee}, e.args...)...)
+	case ListLiteral:
+		return parenthesize("list", e.elements...)
+	case MapLiteral:
+		var entries []Expr
+		for idx := range e.keys {
+			entries = append(entries, e.keys[idx], e.values[idx])
+		}
+		return parenthesize("map", entries...)
+	case Index:
+		return parenthesize("index", e.object, e.index)
+	case SetIndex:
+		return "(:= " + parenthesize("index", e.object, e.index) + " " + ExprString(e.value) + ")"
+	case Slice:
+		return parenthesize("slice", e.object, orNil(e.start), orNil(e.end))
+	case Get:
+		return "(. " + ExprString(e.object) + " " + e.name.literal + ")"
+	case Set:
+		return "(:= " + ExprString(Get{e.object, e.name}) + " " + ExprString(e.value) + ")"
+	case Interpolation:
+		var parts []string
+		for idx, part := range e.parts {
+			if e.specs[idx] != "" {
+				parts = append(parts, "(format "+ExprString(part)+" "+strconv.Quote(e.specs[idx])+")")
+			} else {
+				parts = append(parts, ExprString(part))
+			}
+		}
+		return "(interpolate " + strings.Join(parts, " ") + ")"
+	default:
+		return "(unknown)"
+	}
+}
+
+/*StmtString returns a lisp-like representation of a statement in the same style as ExprString, so
+  int x := 1 + 2 becomes (var int x (+ 1 2)). Nested blocks are printed inline */
+func StmtString(s Stmt) string {
+	switch s := s.(type) {
+	case Print:
+		return parenthesize("print", s.expr)
+	case ExprStmt:
+		return ExprString(s.expr)
+	case VarDeclaration:
+		declaration := "(var " + s.varType.String() + " " + s.identifier.literal
+		if s.initializer != nil {
+			declaration += " " + ExprString(s.initializer)
+		}
+		return declaration + ")"
+	case If:
+		ifStmt := "(if " + ExprString(s.condition) + " " + StmtString(s.ifTrue)
+		if s.ifFalse != nil {
+			ifStmt += " " + StmtString(s.ifFalse)
+		}
+		return ifStmt + ")"
+	case While:
+		return labeled(s.label, "(while "+ExprString(s.condition)+" "+StmtString(s.body)+")")
+	case For:
+		parts := []string{"(for", ExprString(Literal{obj: NIL})}
+		if s.initializer != nil {
+			parts[1] = StmtString(s.initializer)
+		}
+		parts = append(parts, ExprString(orNil(s.condition)), ExprString(orNil(s.increment)), StmtString(s.body))
+		return labeled(s.label, strings.Join(parts, " ")+")")
+	case ForIn:
+		names := make([]string, len(s.names))
+		for idx, name := range s.names {
+			names[idx] = name.literal
+		}
+		loop := "(for-in (" + strings.Join(names, " ") + ") " + ExprString(s.iterable) + " " + StmtString(s.body) + ")"
+		return labeled(s.label, loop)
+	case LoopControl:
+		control := "(" + strings.ToLower(s.keyword.Type.String())
+		if s.label != "" {
+			control += " " + s.label
+		}
+		return control + ")"
+	case Block:
+		return stmtList("block", s.stmts)
+	case FuncDeclaration:
+		name := s.name.literal
+		if s.receiver != nil {
+			name = "(" + paramString(*s.receiver) + ") " + name
+		}
+		params := make([]string, len(s.params))
+		for idx, param := range s.params {
+			params[idx] = paramString(param)
+		}
+		function := "(fn " + name + " (" + strings.Join(params, " ") + ")"
+		if s.returnType != nil {
+			function += " " + s.returnType.String()
+		}
+		return function + " " + stmtList("block", s.body) + ")"
+	case StructDeclaration:
+		fields := make([]string, len(s.fields))
+		for idx, field := range s.fields {
+			fields[idx] = paramString(field)
+		}
+		return "(struct " + s.name.literal + " " + strings.Join(fields, " ") + ")"
+	case Return:
+		if s.value == nil {
+			return "(return)"
+		}
+		return parenthesize("return", s.value)
+	case Try:
+		parts := []string{"(try", stmtList("block", s.body)}
+		if s.catchName != nil {
+			parts = append(parts, stmtList("catch "+s.catchName.literal, s.catchBody))
+		}
+		if s.finally != nil {
+			parts = append(parts, stmtList("finally", s.finally))
+		}
+		return strings.Join(parts, " ") + ")"
+	case Throw:
+		return parenthesize("throw", s.value)
+	case ErrorStmt:
+		return "(error " + strconv.Quote(s.message) + ")"
+	default:
+		return "(unknown)"
+	}
+}
+
+/*stmtList prints a list of statements after a name, like the statements of a block */
+func stmtList(name string, stmts []Stmt) string {
+	var builder strings.Builder
+	builder.WriteString("(" + name)
+	for _, stmt := range stmts {
+		builder.WriteString(" " + StmtString(stmt))
+	}
+	builder.WriteString(")")
+	return builder.String()
+}
+
+/*labeled wraps a loop in its label, if it has one */
+func labeled(label string, loop string) string {
+	if label == "" {
+		return loop
+	}
+	return "(label " + label + " " + loop + ")"
+}
+
+func paramString(param Param) string {
+	return param.varType.String() + " " + param.name.literal
+}
+
+/*orNil stands in a nil literal for an expression which was left out */
+func orNil(e Expr) Expr {
+	if e == nil {
+		return Literal{obj: NIL}
+	}
+	return e
+}
+
+func parenthesize(name string, exprs ...Expr) string {
+	var builder strings.Builder
+	builder.WriteString("(" + name)
+	for _, e := range exprs {
+		builder.WriteString(" " + ExprString(e))
+	}
+	builder.WriteString(")")
+	return builder.String()
+}
+
+/*operatorString returns an operator as it is written in source */
+func operatorString(operator Token) string {
+	switch operator.Type {
+	case AND:
+		return "and"
+	case OR:
+		return "or"
+	case IN:
+		return "in"
+	default:
+		return operator.Type.String()
+	}
+}
@@ -0,0 +1,324 @@
+package butter
+
+import "sort"
+
+/*resolveScope is a local scope being resolved. Its variables are given slots up front, in the order their
+  declarations appear, which is the order the interpreter will define them in */
+type resolveScope struct {
+	slots    map[string]int
+	declared map[string]bool
+	//function is true for the outermost scope of a function, the one holding its receiver or parameters
+	function bool
+}
+
+/*Resolver works out where every variable used by a program lives before it runs, so the interpreter can find
+  local variables by slot instead of searching each environment by name. Names which aren't declared in any
+  enclosing local scope are globals, which are still looked up by name */
+type Resolver struct {
+	globals *Env
+	scopes  []*resolveScope
+	//declaredGlobals are the globals the program has declared so far, pendingGlobals are all of its globals
+	declaredGlobals map[string]bool
+	pendingGlobals  map[string]bool
+	errors          ParseErrors
+}
+
+/*NewResolver returns a resolver for a program which runs with the variables already defined in globals */
+func NewResolver(globals *Env) *Resolver {
+	return &Resolver{
+		globals:         globals,
+		declaredGlobals: make(map[string]bool),
+		pendingGlobals:  make(map[string]bool),
+	}
+}
+
+/*Resolve binds every variable in the program to where it is declared. Undefined variables, variables used
+  before their declaration and variables declared twice in the same scope are returned as ParseErrors */
+func Resolve(stmts []Stmt, globals *Env) error {
+	return NewResolver(globals).Resolve(stmts)
+}
+
+/*Resolve binds every variable in the program to where it is declared, returning any errors found */
+func (r *Resolver) Resolve(stmts []Stmt) error {
+	for _, name := range declaredNames(stmts) {
+		r.pendingGlobals[name.literal] = true
+	}
+	r.resolveStmts(stmts)
+	if len(r.errors) > 0 {
+		errs := r.errors
+		sort.SliceStable(errs, func(a, b int) bool { return errs[a].Pos.Offset < errs[b].Pos.Offset })
+		return errs
+	}
+	return nil
+}
+
+func (r *Resolver) errorAt(pos Position, message string) {
+	r.errors = append(r.errors, &ParseError{pos, message})
+}
+
+/*declaredNames returns the names declared directly by a list of statements, in order. Methods are attached to
+  their struct rather than declared */
+func declaredNames(stmts []Stmt) []Token {
+	var names []Token
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case VarDeclaration:
+			names = append(names, s.identifier)
+		case FuncDeclaration:
+			if s.receiver == nil {
+				names = append(names, s.name)
+			}
+		case StructDeclaration:
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+/*beginScope starts a local scope which will declare the passed names */
+func (r *Resolver) beginScope(names []Token, function bool) {
+	scope := &resolveScope{make(map[string]int, len(names)), make(map[string]bool, len(names)), function}
+	for slot, name := range names {
+		if _, exists := scope.slots[name.literal]; !exists {
+			scope.slots[name.literal] = slot
+		}
+	}
+	r.scopes = append(r.scopes, scope)
+}
+
+func (r *Resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+/*declare marks a variable as declared in the innermost scope, so the code after it can use it */
+func (r *Resolver) declare(name Token) {
+	if len(r.scopes) == 0 {
+		if r.isGlobal(name.literal) {
+			r.errorAt(name.pos, "Variable '"+name.literal+"' already initialized in this scope")
+		}
+		r.declaredGlobals[name.literal] = true
+		return
+	}
+	scope := r.scopes[len(r.scopes)-1]
+	if scope.declared[name.literal] {
+		r.errorAt(name.pos, "Variable '"+name.literal+"' already initialized in this scope")
+	}
+	scope.declared[name.literal] = true
+}
+
+/*resolveName binds a use of a variable to the nearest declaration of it which has already run. A function may
+  use a variable declared after it in an enclosing scope, as it may not be called until the variable exists.
+  Names which aren't declared anywhere the program can see are reported as undefined */
+func (r *Resolver) resolveName(name Token, binding *Binding) {
+	inFunction := false
+	pending := false
+	for idx := len(r.scopes) - 1; idx >= 0; idx-- {
+		scope := r.scopes[idx]
+		if slot, ok := scope.slots[name.literal]; ok {
+			if scope.declared[name.literal] || inFunction {
+				*binding = Binding{len(r.scopes) - 1 - idx, slot}
+				return
+			}
+			pending = true
+		}
+		inFunction = inFunction || scope.function
+	}
+	*binding = Binding{depth: -1}
+	if !pending && !r.pendingGlobals[name.literal] && !r.isGlobal(name.literal) {
+		r.errorAt(name.pos, "Undefined variable '"+name.literal+"'")
+		return
+	}
+	//top level code can see the globals declared before it, functions may run after any of them are declared
+	if !inFunction && r.isGlobal(name.literal) {
+		return
+	}
+	if pending || !inFunction && r.pendingGlobals[name.literal] {
+		r.errorAt(name.pos, "Variable '"+name.literal+"' is used before it is declared")
+	}
+}
+
+/*isGlobal returns true if a global has been declared by the program so far, or by code run before it */
+func (r *Resolver) isGlobal(name string) bool {
+	if r.declaredGlobals[name] {
+		return true
+	}
+	if r.globals != nil {
+		_, defined := r.globals.values[name]
+		return defined
+	}
+	return false
+}
+
+func (r *Resolver) resolveStmts(stmts []Stmt) {
+	for _, stmt := range stmts {
+		r.resolveStmt(stmt)
+	}
+}
+
+/*resolveScoped resolves a list of statements which run in a scope of their own */
+func (r *Resolver) resolveScoped(stmts []Stmt) {
+	r.beginScope(declaredNames(stmts), false)
+	r.resolveStmts(stmts)
+	r.endScope()
+}
+
+/*resolveBody resolves the body of an if statement or loop, which runs in a scope of its own even if it isn't a block */
+func (r *Resolver) resolveBody(body Stmt) {
+	if block, ok := body.(Block); ok {
+		r.resolveScoped(block.stmts)
+		return
+	}
+	r.resolveScoped([]Stmt{body})
+}
+
+func (r *Resolver) resolveStmt(stmt Stmt) {
+	switch s := stmt.(type) {
+	case Print:
+		r.resolveExpr(s.expr)
+	case ExprStmt:
+		r.resolveExpr(s.expr)
+	case VarDeclaration:
+		if s.initializer != nil {
+			r.resolveExpr(s.initializer)
+		}
+		r.declare(s.identifier)
+	case If:
+		r.resolveExpr(s.condition)
+		r.resolveBody(s.ifTrue)
+		if s.ifFalse != nil {
+			r.resolveBody(s.ifFalse)
+		}
+	case While:
+		r.resolveExpr(s.condition)
+		r.resolveBody(s.body)
+	case For:
+		var names []Token
+		if s.initializer != nil {
+			names = declaredNames([]Stmt{s.initializer})
+		}
+		r.beginScope(names, false)
+		if s.initializer != nil {
+			r.resolveStmt(s.initializer)
+		}
+		if s.condition != nil {
+			r.resolveExpr(s.condition)
+		}
+		if s.increment != nil {
+			r.resolveExpr(s.increment)
+		}
+		r.resolveBody(s.body)
+		r.endScope()
+	case ForIn:
+		r.resolveExpr(s.iterable)
+		r.beginScope(s.names, false)
+		for _, name := range s.names {
+			r.declare(name)
+		}
+		r.resolveBody(s.body)
+		r.endScope()
+	case Block:
+		r.resolveScoped(s.stmts)
+	case FuncDeclaration:
+		if s.receiver == nil {
+			r.declare(s.name)
+		}
+		r.resolveFunction(s)
+	case StructDeclaration:
+		r.declare(s.name)
+	case Return:
+		if s.value != nil {
+			r.resolveExpr(s.value)
+		}
+	case Try:
+		r.resolveScoped(s.body)
+		if s.catchName != nil {
+			r.beginScope(append([]Token{*s.catchName}, declaredNames(s.catchBody)...), false)
+			r.declare(*s.catchName)
+			r.resolveStmts(s.catchBody)
+			r.endScope()
+		}
+		if s.finally != nil {
+			r.resolveScoped(s.finally)
+		}
+	case Throw:
+		r.resolveExpr(s.value)
+	}
+}
+
+/*resolveFunction resolves a function body in a scope holding its parameters. A method's receiver is declared in
+  a scope of its own around that, as it is bound before the method is called */
+func (r *Resolver) resolveFunction(fd FuncDeclaration) {
+	if fd.receiver != nil {
+		r.beginScope([]Token{fd.receiver.name}, true)
+		r.declare(fd.receiver.name)
+		defer r.endScope()
+	}
+	names := make([]Token, 0, len(fd.params))
+	for _, param := range fd.params {
+		names = append(names, param.name)
+	}
+	r.beginScope(append(names, declaredNames(fd.body)...), fd.receiver == nil)
+	for _, name := range names {
+		r.declare(name)
+	}
+	r.resolveStmts(fd.body)
+	r.endScope()
+}
+
+func (r *Resolver) resolveExpr(expr Expr) {
+	switch e := expr.(type) {
+	case Variable:
+		r.resolveName(e.identifier, e.binding)
+	case Assign:
+		r.resolveExpr(e.initializer)
+		r.resolveName(e.identifier, e.binding)
+	case Binary:
+		r.resolveExpr(e.left)
+		r.resolveExpr(e.right)
+	case Logical:
+		r.resolveExpr(e.left)
+		r.resolveExpr(e.right)
+	case Unary:
+		r.resolveExpr(e.right)
+	case Grouping:
+		r.resolveExpr(e.expr)
+	case Call:
+		r.resolveExpr(e.callee)
+		for _, arg := range e.args {
+			r.resolveExpr(arg)
+		}
+	case Interpolation:
+		for _, part := range e.parts {
+			r.resolveExpr(part)
+		}
+	case ListLiteral:
+		for _, element := range e.elements {
+			r.resolveExpr(element)
+		}
+	case MapLiteral:
+		for idx := range e.keys {
+			r.resolveExpr(e.keys[idx])
+			r.resolveExpr(e.values[idx])
+		}
+	case Index:
+		r.resolveExpr(e.object)
+		r.resolveExpr(e.index)
+	case SetIndex:
+		r.resolveExpr(e.object)
+		r.resolveExpr(e.index)
+		r.resolveExpr(e.value)
+	case Slice:
+		r.resolveExpr(e.object)
+		if e.start != nil {
+			r.resolveExpr(e.start)
+		}
+		if e.end != nil {
+			r.resolveExpr(e.end)
+		}
+	case Get:
+		r.resolveExpr(e.object)
+	case Set:
+		r.resolveExpr(e.object)
+		r.resolveExpr(e.value)
+	}
+}
@@ -0,0 +1,130 @@
+package butter
+
+import "testing"
+
+/*scopeErrorTests are programs the resolver rejects before they run, along with the error each one reports */
+var scopeErrorTests = []struct {
+	name   string
+	source string
+	err    string
+}{
+	{"global used before declaration", `
+print a
+int a := 1
+`, "PARSE_ERROR [test:2:7]: Variable 'a' is used before it is declared"},
+	{"local used before declaration", `
+{
+  print b
+  int b := 2
+}
+`, "PARSE_ERROR [test:3:9]: Variable 'b' is used before it is declared"},
+	{"variable used in its own initializer", `
+fn f() {
+  int c := c + 1
+}
+`, "PARSE_ERROR [test:3:12]: Variable 'c' is used before it is declared"},
+	{"global declared twice", `
+int d := 1
+int d := 2
+`, "PARSE_ERROR [test:3:5]: Variable 'd' already initialized in this scope"},
+	{"local declared twice", `
+{
+  int e := 1
+  string e := "e"
+}
+`, "PARSE_ERROR [test:4:10]: Variable 'e' already initialized in this scope"},
+	{"parameter declared twice", `
+fn g(int p, int p) {
+}
+`, "PARSE_ERROR [test:2:17]: Variable 'p' already initialized in this scope"},
+	{"loop variable declared twice", `
+for x, x in [1] {
+}
+`, "PARSE_ERROR [test:2:8]: Variable 'x' already initialized in this scope"},
+	{"for loop variable used after the loop", `
+for int i := 0; i < 2; i := i + 1 {
+}
+print i
+`, "PARSE_ERROR [test:4:7]: Undefined variable 'i'"},
+	{"struct declared twice", `
+struct P {int a}
+struct P {int b}
+`, "PARSE_ERROR [test:3:8]: Variable 'P' already initialized in this scope"},
+	{"duplicate field", `
+struct P {int a; string a}
+`, "PARSE_ERROR [test:2:25]: Duplicate field 'a' in struct 'P'"},
+	{"undefined variable", `
+print zz
+`, "PARSE_ERROR [test:2:7]: Undefined variable 'zz'"},
+	{"undefined variable in a function never called", `
+fn f() int {
+  return qq
+}
+`, "PARSE_ERROR [test:3:10]: Undefined variable 'qq'"},
+	{"assignment to undefined variable", `
+y := 5
+`, "PARSE_ERROR [test:2:1]: Undefined variable 'y'"},
+}
+
+func TestScopeErrors(t *testing.T) {
+	for _, test := range scopeErrorTests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, bytecode := range []bool{false, true} {
+				output, err := runConformance(test.source, bytecode)
+				if output != "" {
+					t.Errorf("printed %q before the error was reported", output)
+				}
+				if got := errorString(err); got != test.err {
+					t.Errorf("failed with %q, want %q", got, test.err)
+				}
+			}
+		})
+	}
+}
+
+func TestForwardReferences(t *testing.T) {
+	source := `
+{
+  fn even(int n) bool {
+    if n == 0 {
+      return true
+    }
+    return odd(n - 1)
+  }
+  fn odd(int n) bool {
+    if n == 0 {
+      return false
+    }
+    return even(n - 1)
+  }
+  print even(10)
+}
+fn later() {
+  print y
+}
+int y := 7
+later()
+{
+  fn early() int {
+    return z
+  }
+  try {
+    print early()
+  } catch (e) {
+    print e
+  }
+  int z := 9
+  print early()
+}
+`
+	want := "TRUE\n7\nRuntimeError: Undefined variable: 'z'\n9\n"
+	for _, bytecode := range []bool{false, true} {
+		output, err := runConformance(source, bytecode)
+		if err != nil {
+			t.Fatalf("failed with %v", err)
+		}
+		if output != want {
+			t.Errorf("printed %q, want %q", output, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package butter
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+/*Source is a named piece of Butter code which tokens and errors point back into */
+type Source struct {
+	Name string
+	Text string
+}
+
+/*NewSource creates a source from a name (usually a file path) and its text */
+func NewSource(name string, text string) *Source {
+	return &Source{name, text}
+}
+
+/*Line returns the text of a line in the source, counting from 1, without its line ending */
+func (s *Source) Line(n int) string {
+	lines := strings.Split(s.Text, "\n")
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+	return strings.TrimRight(lines[n-1], "\r")
+}
+
+/*Position is a location within a source, along with the number of bytes of source text it spans */
+type Position struct {
+	Source *Source
+	Line   int
+	Column int
+	Offset int
+	Length int
+}
+
+/*String returns the position in file:line:column form */
+func (p Position) String() string {
+	name := "<unknown>"
+	if p.Source != nil {
+		name = p.Source.Name
+	}
+	return fmt.Sprintf("%s:%d:%d", name, p.Line, p.Column)
+}
+
+/*Snippet returns the source line the position is on, underlined from the start of the position with
+  a caret followed by tildes for the rest of its span */
+func (p Position) Snippet() string {
+	if p.Source == nil || p.Line < 1 {
+		return ""
+	}
+	line := p.Source.Line(p.Line)
+	gutter := fmt.Sprintf("%5d | ", p.Line)
+	var underline strings.Builder
+	//keep tabs so the caret lines up with the text above it
+	for idx, r := range []rune(line) {
+		if idx >= p.Column-1 {
+			break
+		}
+		if r == '\t' {
+			underline.WriteRune('\t')
+		} else {
+			underline.WriteRune(' ')
+		}
+	}
+	underline.WriteRune('^')
+	width := 1
+	if end := p.Offset + p.Length; p.Offset <= len(p.Source.Text) {
+		if end > len(p.Source.Text) {
+			end = len(p.Source.Text)
+		}
+		width = utf8.RuneCountInString(p.Source.Text[p.Offset:end])
+	}
+	//the underline never runs past the end of the line
+	if rest := utf8.RuneCountInString(line) - p.Column + 1; width > rest {
+		width = rest
+	}
+	if width > 1 {
+		underline.WriteString(strings.Repeat("~", width-1))
+	}
+	return gutter + line + "\n" + strings.Repeat(" ", len(gutter)-2) + "| " + underline.String()
+}
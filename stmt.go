@@ -1,13 +1,15 @@
-package main
+package butter
 
-/*Expr defines an object which can accept an interpreter and return an object */
+/*Stmt defines an object which can accept an interpreter and knows where it begins in the source */
 type Stmt interface {
 	Accept(interpreter *Interpreter)
+	Position() Position
 }
 
 /*Print contains an expr, and will evaluate and print the expression */
 type Print struct {
-	expr Expr
+	keyword Token
+	expr    Expr
 }
 
 /*ExprStmt contains an expr which will be evaluated */
@@ -15,33 +17,110 @@ type ExprStmt struct {
 	expr Expr
 }
 
+/*VarDeclaration declares a typed variable, its initializer is nil if the variable starts at its type's zero value.
+  The type of a variable declared with var or let is inferred from its initializer */
 type VarDeclaration struct {
-	tokenType   Token
+	varType     TypeSpec
 	identifier  Token
 	initializer Expr
 }
 
 type If struct {
+	keyword   Token
 	condition Expr
 	ifTrue    Stmt
 	ifFalse   Stmt
 }
 
 type While struct {
+	keyword   Token
+	label     string
 	condition Expr
 	body      Stmt
 }
 
+/*For runs its initializer once, then its body and increment for as long as its condition is true. The
+  initializer, condition and increment are all optional */
+type For struct {
+	keyword     Token
+	label       string
+	initializer Stmt
+	condition   Expr
+	increment   Expr
+	body        Stmt
+}
+
+/*ForIn runs its body once for each element of a list or each entry of a map, in insertion order */
+type ForIn struct {
+	keyword  Token
+	label    string
+	names    []Token
+	iterable Expr
+	body     Stmt
+}
+
+/*LoopControl is a break or continue statement, optionally naming the labeled loop it applies to */
+type LoopControl struct {
+	keyword Token
+	label   string
+}
+
 type Block struct {
+	brace Token
 	stmts []Stmt
 }
 
+/*Param is a single typed parameter in a function declaration */
+type Param struct {
+	varType TypeSpec
+	name    Token
+}
+
+/*FuncDeclaration declares a named function with typed parameters, an optional return type and a body.
+  Methods also have a receiver, the struct instance they are called on */
+type FuncDeclaration struct {
+	receiver   *Param
+	name       Token
+	params     []Param
+	returnType *TypeSpec
+	body       []Stmt
+}
+
+/*StructDeclaration declares a named record type with typed fields */
+type StructDeclaration struct {
+	keyword Token
+	name    Token
+	fields  []Param
+}
+
+/*Return exits the enclosing function, handing back the value of its expr (if any) */
+type Return struct {
+	keyword Token
+	value   Expr
+}
+
+/*Try runs its body, handing any error raised to the catch block and always running the finally block */
+type Try struct {
+	keyword   Token
+	body      []Stmt
+	catchName *Token
+	catchBody []Stmt
+	finally   []Stmt
+}
+
+/*Throw raises an error built from the value of its expr */
+type Throw struct {
+	keyword Token
+	value   Expr
+}
+
 type ErrorStmt struct {
+	pos     Position
 	message string
 }
 
 /*Accept finds the visitPrint method on the interpreter */
-func (p Print) Accept(intepreter *Interpreter) {
+func (p Print) Accept(interpreter *Interpreter) {
 	interpreter.visitPrint(p)
 }
 
@@ -61,10 +140,117 @@ func (w While) Accept(interpreter *Interpreter) {
 	interpreter.visitWhile(w)
 }
 
+func (f For) Accept(interpreter *Interpreter) {
+	interpreter.visitFor(f)
+}
+
+func (f ForIn) Accept(interpreter *Interpreter) {
+	interpreter.visitForIn(f)
+}
+
+func (l LoopControl) Accept(interpreter *Interpreter) {
+	interpreter.visitLoopControl(l)
+}
+
 func (b Block) Accept(interpreter *Interpreter) {
 	interpreter.visitBlock(b)
 }
 
+func (fd FuncDeclaration) Accept(interpreter *Interpreter) {
+	interpreter.visitFuncDeclaration(fd)
+}
+
+func (sd StructDeclaration) Accept(interpreter *Interpreter) {
+	interpreter.visitStructDeclaration(sd)
+}
+
+func (r Return) Accept(interpreter *Interpreter) {
+	interpreter.visitReturn(r)
+}
+
+func (t Try) Accept(interpreter *Interpreter) {
+	interpreter.visitTry(t)
+}
+
+func (t Throw) Accept(interpreter *Interpreter) {
+	interpreter.visitThrow(t)
+}
+
 func (e ErrorStmt) Accept(interpreter *Interpreter) {
 	interpreter.visitErrorStmt(e)
 }
+
+/*Position returns the location of the print keyword */
+func (p Print) Position() Position {
+	return p.keyword.pos
+}
+
+/*Position returns the location of the expression being evaluated */
+func (e ExprStmt) Position() Position {
+	return e.expr.Position()
+}
+
+/*Position returns the location of the declared variable's name */
+func (vd VarDeclaration) Position() Position {
+	return vd.identifier.pos
+}
+
+/*Position returns the location of the if keyword */
+func (i If) Position() Position {
+	return i.keyword.pos
+}
+
+/*Position returns the location of the while keyword */
+func (w While) Position() Position {
+	return w.keyword.pos
+}
+
+/*Position returns the location of the for keyword */
+func (f For) Position() Position {
+	return f.keyword.pos
+}
+
+/*Position returns the location of the for keyword */
+func (f ForIn) Position() Position {
+	return f.keyword.pos
+}
+
+/*Position returns the location of the break or continue keyword */
+func (l LoopControl) Position() Position {
+	return l.keyword.pos
+}
+
+/*Position returns the location of the block's opening brace */
+func (b Block) Position() Position {
+	return b.brace.pos
+}
+
+/*Position returns the location of the declared function's name */
+func (fd FuncDeclaration) Position() Position {
+	return fd.name.pos
+}
+
+/*Position returns the location of the declared struct's name */
+func (sd StructDeclaration) Position() Position {
+	return sd.name.pos
+}
+
+/*Position returns the location of the return keyword */
+func (r Return) Position() Position {
+	return r.keyword.pos
+}
+
+/*Position returns the location of the try keyword */
+func (t Try) Position() Position {
+	return t.keyword.pos
+}
+
+/*Position returns the location of the throw keyword */
+func (t Throw) Position() Position {
+	return t.keyword.pos
+}
+
+/*Position returns the location of the syntax error */
+func (e ErrorStmt) Position() Position {
+	return e.pos
+}
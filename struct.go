@@ -0,0 +1,72 @@
+package butter
+
+import "fmt"
+
+/*Struct is a user-defined record type along with the methods declared on it. Calling a struct constructs
+  a new instance from a value for each of its fields, in the order they were declared */
+type Struct struct {
+	declaration StructDeclaration
+	methods     map[string]Method
+}
+
+/*Method is a function declared with a receiver, which is bound to an instance when it is looked up */
+type Method interface {
+	Declaration() FuncDeclaration
+	Bind(instance *Instance) Object
+}
+
+/*Instance is a value of a user-defined struct type */
+type Instance struct {
+	structType *Struct
+	fields     map[string]Object
+}
+
+/*Type returns a string representation of the struct object's type */
+func (s *Struct) Type() string {
+	return string(STRUCTOBJ)
+}
+
+/*Arity returns the number of fields the constructor expects */
+func (s *Struct) Arity() int {
+	return len(s.declaration.fields)
+}
+
+/*Field returns the declared field with the passed name, and whether there was one */
+func (s *Struct) Field(name string) (Param, bool) {
+	for _, field := range s.declaration.fields {
+		if field.name.literal == name {
+			return field, true
+		}
+	}
+	return Param{}, false
+}
+
+/*AddMethod attaches a method to the struct, which must not share its name with a field or another method */
+func (s *Struct) AddMethod(name string, method Method) {
+	structName := s.declaration.name.literal
+	if _, isField := s.Field(name); isField {
+		runtimeError("'" + structName + "' already has a field named '" + name + "'")
+	}
+	if _, exists := s.methods[name]; exists {
+		runtimeError("'" + structName + "' already has a method named '" + name + "'")
+	}
+	s.methods[name] = method
+}
+
+/*Call builds a new instance, checking each argument against the type of its field */
+func (s *Struct) Call(interpreter *Interpreter, args []Object) Object {
+	instance := &Instance{s, make(map[string]Object, len(args))}
+	for idx, field := range s.declaration.fields {
+		if !IsVarType(field.varType, args[idx]) {
+			typeError(fmt.Sprintf("field '%s' of '%s' must be of type %s", field.name.literal, s.declaration.name.literal, field.varType))
+		}
+		PinType(field.varType, args[idx])
+		instance.fields[field.name.literal] = args[idx]
+	}
+	return instance
+}
+
+/*Type returns the name of the instance's struct */
+func (in *Instance) Type() string {
+	return in.structType.declaration.name.literal
+}
@@ -1,7 +1,11 @@
-package main
+package butter
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 /*TokenType is an alias to create a const based enum to determine the type of token */
@@ -39,11 +43,34 @@ const (
 	FALSE
 	ASSIGN
 	STRING
+	INTERPOLATION
 	INTTYPE
 	FLOATTYPE
 	BOOLTYPE
 	STRINGTYPE
+	LISTTYPE
+	MAPTYPE
+	STRUCT
+	VAR
+	LET
+	IN
+	LEFTBRACKET
+	RIGHTBRACKET
 	IDENTIFIER
+	COMMA
+	FN
+	RETURN
+	TRY
+	CATCH
+	FINALLY
+	THROW
+	DOT
+	FOR
+	BREAK
+	CONTINUE
+	SEMICOLON
+	COLON
+	COMMENT
 	NEWLINE
 	EOF
 )
@@ -61,7 +88,7 @@ func (t TokenType) String() string {
 	case DIV:
 		return "/"
 	case MOD:
-		return "%%"
+		return "%"
 	case EQUAL:
 		return "="
 	case LEFTGROUP:
@@ -110,6 +137,8 @@ func (t TokenType) String() string {
 		return "ASSIGN"
 	case STRING:
 		return "STRING"
+	case INTERPOLATION:
+		return "INTERPOLATION"
 	case INTTYPE:
 		return "INTTYPE"
 	case FLOATTYPE:
@@ -118,8 +147,52 @@ func (t TokenType) String() string {
 		return "BOOLTYPE"
 	case STRINGTYPE:
 		return "STRINGTYPE"
+	case LISTTYPE:
+		return "LISTTYPE"
+	case MAPTYPE:
+		return "MAPTYPE"
+	case STRUCT:
+		return "STRUCT"
+	case VAR:
+		return "VAR"
+	case LET:
+		return "LET"
+	case IN:
+		return "IN"
+	case LEFTBRACKET:
+		return "["
+	case RIGHTBRACKET:
+		return "]"
 	case IDENTIFIER:
 		return "IDENTIFIER"
+	case COMMA:
+		return ","
+	case FN:
+		return "FN"
+	case RETURN:
+		return "RETURN"
+	case TRY:
+		return "TRY"
+	case CATCH:
+		return "CATCH"
+	case FINALLY:
+		return "FINALLY"
+	case THROW:
+		return "THROW"
+	case DOT:
+		return "."
+	case FOR:
+		return "FOR"
+	case BREAK:
+		return "BREAK"
+	case CONTINUE:
+		return "CONTINUE"
+	case SEMICOLON:
+		return ";"
+	case COLON:
+		return ":"
+	case COMMENT:
+		return "COMMENT"
 	case NEWLINE:
 		return "\\n"
 	case EOF:
@@ -129,11 +202,21 @@ func (t TokenType) String() string {
 	}
 }
 
-/*Token contains the Type of the token object and the value stored there */
+/*Token contains the Type of the token object, the value stored there and where it was found in the source */
 type Token struct {
 	Type    TokenType
 	literal string
-	line    int
+	pos     Position
+	parts   []StringPart
+}
+
+/*StringPart is a piece of an interpolated string, either literal text or the tokens of an embedded expression
+  along with its optional format specifier */
+type StringPart struct {
+	text    string
+	tokens  []Token
+	spec    string
+	specPos Position
 }
 
 func (t Token) String() string {
@@ -198,6 +281,8 @@ func (t Token) String() string {
 		return "Token: ASSIGN; literal ->" + t.literal
 	case STRING:
 		return "Token: STRING; literal ->" + t.literal
+	case INTERPOLATION:
+		return "Token: INTERPOLATION; literal ->" + t.literal
 	case INTTYPE:
 		return "Token: INTTYPE; literal ->" + t.literal
 	case FLOATTYPE:
@@ -206,8 +291,52 @@ func (t Token) String() string {
 		return "Token: BOOLTYPE; literal ->" + t.literal
 	case STRINGTYPE:
 		return "Token: STRINGTYPE; literal ->" + t.literal
+	case LISTTYPE:
+		return "Token: LISTTYPE; literal ->" + t.literal
+	case MAPTYPE:
+		return "Token: MAPTYPE; literal ->" + t.literal
+	case STRUCT:
+		return "Token: STRUCT; literal ->" + t.literal
+	case VAR:
+		return "Token: VAR; literal ->" + t.literal
+	case LET:
+		return "Token: LET; literal ->" + t.literal
+	case IN:
+		return "Token: IN; literal ->" + t.literal
+	case LEFTBRACKET:
+		return "Token: LEFTBRACKET; literal ->" + t.literal
+	case RIGHTBRACKET:
+		return "Token: RIGHTBRACKET; literal ->" + t.literal
 	case IDENTIFIER:
 		return "Token: IDENTIFIER; literal ->" + t.literal
+	case COMMA:
+		return "Token: COMMA; literal ->" + t.literal
+	case FN:
+		return "Token: FN; literal ->" + t.literal
+	case RETURN:
+		return "Token: RETURN; literal ->" + t.literal
+	case TRY:
+		return "Token: TRY; literal ->" + t.literal
+	case CATCH:
+		return "Token: CATCH; literal ->" + t.literal
+	case FINALLY:
+		return "Token: FINALLY; literal ->" + t.literal
+	case THROW:
+		return "Token: THROW; literal ->" + t.literal
+	case DOT:
+		return "Token: DOT; literal ->" + t.literal
+	case FOR:
+		return "Token: FOR; literal ->" + t.literal
+	case BREAK:
+		return "Token: BREAK; literal ->" + t.literal
+	case CONTINUE:
+		return "Token: CONTINUE; literal ->" + t.literal
+	case SEMICOLON:
+		return "Token: SEMICOLON; literal ->" + t.literal
+	case COLON:
+		return "Token: COLON; literal ->" + t.literal
+	case COMMENT:
+		return "Token: COMMENT; literal ->" + t.literal
 	case NEWLINE:
 		return "Token: NEWLINE; literal ->" + t.literal
 	case EOF:
@@ -219,38 +348,80 @@ func (t Token) String() string {
 
 /*Tokenizer contains a list of tokens and information about the line currently being parsed */
 type Tokenizer struct {
+	source      *Source
 	inputString string
 	tokens      []Token
 	begTok      int
 	cursorLoc   int
 	cursor      byte
 	lineNo      int
+	lineStart   int
+	tokLine     int
+	tokStart    int
+	comments    bool
+	errors      []*ParseError
+}
+
+/*reserved maps each reserved word to the type of token it is read as */
+var reserved = map[string]TokenType{
+	"print":    PRINT,
+	"if":       IF,
+	"else":     ELSE,
+	"while":    WHILE,
+	"or":       OR,
+	"and":      AND,
+	"true":     TRUE,
+	"false":    FALSE,
+	"int":      INTTYPE,
+	"float":    FLOATTYPE,
+	"bool":     BOOLTYPE,
+	"string":   STRINGTYPE,
+	"list":     LISTTYPE,
+	"map":      MAPTYPE,
+	"struct":   STRUCT,
+	"var":      VAR,
+	"let":      LET,
+	"in":       IN,
+	"fn":       FN,
+	"return":   RETURN,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
+	"for":      FOR,
+	"break":    BREAK,
+	"continue": CONTINUE,
 }
 
-var reserved map[string]TokenType
+/*ReservedWords returns every reserved word of the language, sorted */
+func ReservedWords() []string {
+	words := make([]string, 0, len(reserved))
+	for word := range reserved {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
 
 /*NewTokenizer creates a tokenizer struct and initializes all of its fields to their default values*/
-func NewTokenizer(inputString string) Tokenizer {
-	reserved = make(map[string]TokenType)
-	reserved["print"] = PRINT
-	reserved["if"] = IF
-	reserved["else"] = ELSE
-	reserved["while"] = WHILE
-	reserved["or"] = OR
-	reserved["and"] = AND
-	reserved["true"] = TRUE
-	reserved["false"] = FALSE
-	reserved["int"] = INTTYPE
-	reserved["float"] = FLOATTYPE
-	reserved["bool"] = BOOLTYPE
-	reserved["string"] = STRINGTYPE
-	return Tokenizer{inputString, []Token{}, 0, 0, '0', 0}
+func NewTokenizer(source *Source) Tokenizer {
+	return Tokenizer{source, source.Text, []Token{}, 0, 0, '0', 1, 0, 1, 0, false, nil}
+}
+
+/*KeepComments makes the tokenizer emit comments as COMMENT tokens rather than discarding them. The parser
+  does not accept COMMENT tokens, they are meant for tools which need to reproduce the source */
+func (t *Tokenizer) KeepComments(keep bool) {
+	t.comments = keep
 }
 
 /*Tokenize takes in an entire program as a string argument and parses it into tokens
   which it stores in the Tokens field of the tokenizer object it is called on */
 func (t *Tokenizer) Tokenize() []Token {
-	for cursor := t.Advance(); !t.AtEnd(); cursor = t.Advance() {
+	for !t.AtEnd() {
+		//remember which line the token starts on, in case it spans several
+		t.tokLine = t.lineNo
+		t.tokStart = t.lineStart
+		cursor := t.Advance()
 		switch cursor {
 		case 0:
 			t.AddToken(EOF, "")
@@ -260,8 +431,8 @@ func (t *Tokenizer) Tokenize() []Token {
 			t.begTok++
 			continue
 		case '\n':
-			t.lineNo++
 			t.AddToken(NEWLINE, "")
+			t.Newline()
 		case '+':
 			t.AddToken(PLUS, "")
 		case '-':
@@ -273,7 +444,13 @@ func (t *Tokenizer) Tokenize() []Token {
 				t.AddToken(MULT, "")
 			}
 		case '/':
-			t.AddToken(DIV, "")
+			if t.Match('/') {
+				t.LineComment()
+			} else if t.Match('*') {
+				t.BlockComment()
+			} else {
+				t.AddToken(DIV, "")
+			}
 		case '%':
 			t.AddToken(MOD, "")
 		case '(':
@@ -284,6 +461,14 @@ func (t *Tokenizer) Tokenize() []Token {
 			t.AddToken(LEFTBRACE, "")
 		case '}':
 			t.AddToken(RIGHTBRACE, "")
+		case '[':
+			t.AddToken(LEFTBRACKET, "")
+		case ']':
+			t.AddToken(RIGHTBRACKET, "")
+		case ',':
+			t.AddToken(COMMA, "")
+		case '.':
+			t.AddToken(DOT, "")
 		case '!':
 			if t.Match('=') {
 				t.AddToken(BANGEQUAL, "")
@@ -294,7 +479,7 @@ func (t *Tokenizer) Tokenize() []Token {
 			if t.Match('=') {
 				t.AddToken(EQUALEQUAL, "")
 			} else {
-				ParseError(t.lineNo, "Expect '=' after '='")
+				t.Error("Expect '=' after '='")
 			}
 		case '>':
 			if t.Match('=') {
@@ -312,30 +497,303 @@ func (t *Tokenizer) Tokenize() []Token {
 			if t.Match('=') {
 				t.AddToken(ASSIGN, "")
 			} else {
-				ParseError(t.lineNo, "Expect '=' after ':'")
+				t.AddToken(COLON, "")
 			}
+		case ';':
+			t.AddToken(SEMICOLON, "")
 		case '"':
-			for !t.Match('"') {
+			if t.PeekNext() == '"' && t.PeekAt(1) == '"' {
 				t.Advance()
-				if t.AtEnd() {
-					ParseError(t.lineNo, "Unclosed string literal")
-				}
+				t.Advance()
+				t.MultilineString()
+			} else {
+				t.StringLiteral()
 			}
-			t.AddToken(STRING, t.inputString[t.begTok+1:t.cursorLoc-1])
+		case '`':
+			t.RawString()
 		default:
 			if IsNum(cursor) {
 				t.Number()
 			} else if IsAlpha(cursor) {
 				t.IdentifierOrReserved()
 			} else {
-				ParseError(t.lineNo+1, fmt.Sprintf("near -> '%c'", t.inputString[t.cursorLoc-1]))
+				//report a multibyte character once, rather than once for each of its bytes
+				r, size := utf8.DecodeRuneInString(t.inputString[t.cursorLoc-1:])
+				for i := 1; i < size; i++ {
+					t.Advance()
+				}
+				t.Error(fmt.Sprintf("near -> '%c'", r))
 			}
 		}
 	}
+	//the end of the source is on the last line, not the line of the token before it
+	t.tokLine = t.lineNo
+	t.tokStart = t.lineStart
 	t.AddToken(EOF, "")
 	return t.tokens
 }
 
+/*ErrorAt records a tokenizing error at a position inside the token being read, without abandoning the token */
+func (t *Tokenizer) ErrorAt(pos Position, message string) {
+	t.errors = append(t.errors, &ParseError{pos, message})
+}
+
+/*Errors returns every error encountered while tokenizing */
+func (t *Tokenizer) Errors() []*ParseError {
+	return t.errors
+}
+
+/*Error records a tokenizing error and skips past the offending characters so tokenizing can continue */
+func (t *Tokenizer) Error(message string) {
+	t.errors = append(t.errors, &ParseError{t.Position(), message})
+	t.begTok = t.cursorLoc
+}
+
+/*Newline records that the cursor has moved past the end of a line */
+func (t *Tokenizer) Newline() {
+	t.lineNo++
+	t.lineStart = t.cursorLoc
+}
+
+/*LineComment eats characters up to the end of the line, leaving the newline to be tokenized */
+func (t *Tokenizer) LineComment() {
+	for t.PeekNext() != '\n' && !t.AtEnd() {
+		t.Advance()
+	}
+	t.AddComment()
+}
+
+/*BlockComment eats characters until the matching close of the comment, allowing comments to be nested */
+func (t *Tokenizer) BlockComment() {
+	depth := 1
+	for depth > 0 {
+		if t.AtEnd() {
+			t.Error("Unclosed block comment")
+			return
+		}
+		switch t.Advance() {
+		case '\n':
+			t.Newline()
+		case '/':
+			if t.Match('*') {
+				depth++
+			}
+		case '*':
+			if t.Match('/') {
+				depth--
+			}
+		}
+	}
+	t.AddComment()
+}
+
+/*AddComment adds the comment just read as a token when comments are being kept, otherwise it is skipped */
+func (t *Tokenizer) AddComment() {
+	if t.comments {
+		t.AddToken(COMMENT, t.inputString[t.begTok:t.cursorLoc])
+	} else {
+		t.begTok = t.cursorLoc
+	}
+}
+
+/*StringLiteral eats characters until the closing quote, decoding escape sequences, then creates a new string token.
+  A string may not run past the end of its line */
+func (t *Tokenizer) StringLiteral() {
+	var value strings.Builder
+	var parts []StringPart
+	for !t.Match('"') {
+		if t.AtEnd() || t.PeekNext() == '\n' {
+			t.Error("Unclosed string literal, use \"\"\" for strings spanning several lines")
+			return
+		}
+		switch t.Advance() {
+		case '\\':
+			t.Escape(&value)
+		case '$':
+			t.Dollar(&value, &parts)
+		default:
+			value.WriteByte(t.cursor)
+		}
+	}
+	t.AddString(value.String(), parts)
+}
+
+/*MultilineString eats characters until three closing quotes, decoding escape sequences and counting the lines
+  it passes over, then creates a new string token */
+func (t *Tokenizer) MultilineString() {
+	var value strings.Builder
+	var parts []StringPart
+	for !(t.PeekNext() == '"' && t.PeekAt(1) == '"' && t.PeekAt(2) == '"') {
+		if t.AtEnd() {
+			t.Error("Unclosed multi-line string literal")
+			return
+		}
+		switch t.Advance() {
+		case '\\':
+			t.Escape(&value)
+		case '$':
+			t.Dollar(&value, &parts)
+		case '\n':
+			t.Newline()
+			value.WriteByte('\n')
+		default:
+			value.WriteByte(t.cursor)
+		}
+	}
+	t.Advance()
+	t.Advance()
+	t.Advance()
+	t.AddString(value.String(), parts)
+}
+
+/*AddString adds a plain string token, or an interpolation token if the string embedded any expressions */
+func (t *Tokenizer) AddString(value string, parts []StringPart) {
+	if parts == nil {
+		t.AddToken(STRING, value)
+		return
+	}
+	if value != "" {
+		parts = append(parts, StringPart{text: value})
+	}
+	t.AddToken(INTERPOLATION, "")
+	t.tokens[len(t.tokens)-1].parts = parts
+}
+
+/*Dollar handles a '$' inside a string, which begins an interpolated expression when followed by '{' */
+func (t *Tokenizer) Dollar(value *strings.Builder, parts *[]StringPart) {
+	if !t.Match('{') {
+		value.WriteByte('$')
+		return
+	}
+	if value.Len() > 0 {
+		*parts = append(*parts, StringPart{text: value.String()})
+		value.Reset()
+	}
+	start := t.cursorLoc - 2
+	exprStart := t.cursorLoc
+	exprEnd := -1
+	depth := 0
+	for {
+		if t.AtEnd() || t.PeekNext() == '\n' {
+			t.ErrorAt(t.PositionAt(start, 2), "Unclosed '${' in string")
+			return
+		}
+		c := t.Advance()
+		if c == '}' && depth == 0 {
+			break
+		}
+		switch c {
+		case '{', '(', '[':
+			depth++
+		case '}', ')', ']':
+			depth--
+		case ':':
+			//a colon outside of any brackets separates the expression from its format specifier
+			if depth == 0 && exprEnd == -1 && t.PeekNext() != '=' {
+				exprEnd = t.cursorLoc - 1
+			}
+		case '"':
+			for !t.Match('"') && !t.AtEnd() && t.PeekNext() != '\n' {
+				if t.Advance() == '\\' && t.PeekNext() != '\n' {
+					t.Advance()
+				}
+			}
+		}
+	}
+	part := StringPart{}
+	if exprEnd == -1 {
+		exprEnd = t.cursorLoc - 1
+	} else {
+		part.spec = t.inputString[exprEnd+1 : t.cursorLoc-1]
+		part.specPos = t.PositionAt(exprEnd+1, len(part.spec))
+	}
+	sub := t.SubTokenizer(exprStart, exprEnd)
+	part.tokens = sub.Tokenize()
+	t.errors = append(t.errors, sub.errors...)
+	*parts = append(*parts, part)
+}
+
+/*SubTokenizer returns a tokenizer for a span of the line the cursor is on, positioning its tokens within the
+  whole source */
+func (t *Tokenizer) SubTokenizer(start int, end int) Tokenizer {
+	sub := NewTokenizer(t.source)
+	sub.inputString = t.inputString[:end]
+	sub.begTok = start
+	sub.cursorLoc = start
+	sub.lineNo = t.lineNo
+	sub.lineStart = t.lineStart
+	return sub
+}
+
+/*RawString eats characters until the closing backtick without decoding any escape sequences, then creates a new
+  string token. Raw strings may span several lines */
+func (t *Tokenizer) RawString() {
+	for !t.Match('`') {
+		if t.AtEnd() {
+			t.Error("Unclosed raw string literal")
+			return
+		}
+		if t.Advance() == '\n' {
+			t.Newline()
+		}
+	}
+	t.AddToken(STRING, t.inputString[t.begTok+1:t.cursorLoc-1])
+}
+
+/*Escape decodes the escape sequence following a backslash into the string being built. Invalid escapes are
+  reported and left out of the string */
+func (t *Tokenizer) Escape(value *strings.Builder) {
+	start := t.cursorLoc - 1
+	if t.AtEnd() {
+		return
+	}
+	switch c := t.Advance(); c {
+	case '\n':
+		//a backslash at the end of a line continues the string on the next one, leaving out the line break
+		t.Newline()
+	case 'n':
+		value.WriteByte('\n')
+	case 't':
+		value.WriteByte('\t')
+	case 'r':
+		value.WriteByte('\r')
+	case '0':
+		value.WriteByte(0)
+	case '\\', '"', '\'', '$':
+		value.WriteByte(c)
+	case 'x':
+		t.UnicodeEscape(value, start, 2)
+	case 'u':
+		t.UnicodeEscape(value, start, 4)
+	case 'U':
+		t.UnicodeEscape(value, start, 8)
+	default:
+		t.ErrorAt(t.PositionAt(start, t.cursorLoc-start), fmt.Sprintf("Invalid escape sequence '\\%c'", c))
+	}
+}
+
+/*UnicodeEscape reads the given number of hex digits and writes the code point they spell out */
+func (t *Tokenizer) UnicodeEscape(value *strings.Builder, start int, digits int) {
+	for idx := 0; idx < digits; idx++ {
+		if !IsHex(t.PeekNext()) {
+			t.ErrorAt(t.PositionAt(start, t.cursorLoc-start), fmt.Sprintf("Escape sequence '%s' expects %d hex digits", t.inputString[start:t.cursorLoc], digits))
+			return
+		}
+		t.Advance()
+	}
+	code, _ := strconv.ParseUint(t.inputString[t.cursorLoc-digits:t.cursorLoc], 16, 32)
+	if !utf8.ValidRune(rune(code)) {
+		t.ErrorAt(t.PositionAt(start, t.cursorLoc-start), fmt.Sprintf("Escape sequence '%s' is not a valid code point", t.inputString[start:t.cursorLoc]))
+		return
+	}
+	value.WriteRune(rune(code))
+}
+
+/*IsHex returns true if the byte passed is a hexadecimal digit */
+func IsHex(c byte) bool {
+	return IsNum(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
 /*IsNum returns true if the byte passes is a char corresponding to the numbers 0 through 9*/
 func IsNum(b byte) bool {
 	val := b - '0'
@@ -353,21 +811,62 @@ func IsAlphaNum(c byte) bool {
 	return IsAlpha(c) || IsNum(c)
 }
 
-/*Number eats characters until it reaches a non-numeric character, then creates a new token*/
+/*Number eats the characters of a numeric literal, then creates a new token. Integers may have a 0x, 0o or 0b
+  prefix, floats may have an exponent, and both may separate digits with underscores. The literal is checked
+  and converted by the parser */
 func (t *Tokenizer) Number() {
-	for !t.AtEnd() && IsNum(t.cursor) && IsNum(t.PeekNext()) {
+	if t.cursor == '0' && strings.IndexByte("xXoObB", t.PeekNext()) != -1 {
+		t.Advance()
+		t.Digits()
+		t.AddToken(INT, t.inputString[t.begTok:t.cursorLoc])
+		return
+	}
+	t.Digits()
+	tokenType := INT
+	//a dot followed by a letter is a property access rather than a fraction, unless the letter starts an exponent
+	if t.PeekNext() == '.' && (!IsAlpha(t.PeekAt(1)) || t.ExponentAt(1)) {
+		tokenType = FLOAT
 		t.Advance()
+		t.Digits()
 	}
-	potFloatingPoint := t.cursorLoc
-	if t.Match('.') {
-		for !t.AtEnd() && IsNum(t.PeekNext()) {
+	if t.PeekNext() == 'e' || t.PeekNext() == 'E' {
+		tokenType = FLOAT
+		t.Advance()
+		if t.PeekNext() == '+' || t.PeekNext() == '-' {
 			t.Advance()
 		}
-		t.AddToken(FLOAT, t.inputString[t.begTok:potFloatingPoint]+"."+t.inputString[potFloatingPoint+1:t.cursorLoc])
-	} else {
-		t.AddToken(INT, t.inputString[t.begTok:t.cursorLoc])
+		t.Digits()
 	}
+	t.AddToken(tokenType, t.inputString[t.begTok:t.cursorLoc])
+}
 
+/*ExponentAt returns true if the characters the given distance past the next character are an exponent, an e
+  followed by digits with an optional sign */
+func (t *Tokenizer) ExponentAt(distance int) bool {
+	if c := t.PeekAt(distance); c != 'e' && c != 'E' {
+		return false
+	}
+	next := t.PeekAt(distance + 1)
+	if next == '+' || next == '-' {
+		next = t.PeekAt(distance + 2)
+	}
+	return IsNum(next)
+}
+
+/*Digits eats letters, digits and underscores, leaving the parser to reject any which are out of place */
+func (t *Tokenizer) Digits() {
+	for IsAlphaNum(t.PeekNext()) || t.PeekNext() == '_' {
+		//an exponent is handled by Number
+		if c := t.PeekNext(); (c == 'e' || c == 'E') && !t.HasPrefix() {
+			return
+		}
+		t.Advance()
+	}
+}
+
+/*HasPrefix returns true if the number being read started with a base prefix such as 0x */
+func (t *Tokenizer) HasPrefix() bool {
+	return t.cursorLoc-t.begTok >= 2 && t.inputString[t.begTok] == '0' && IsAlpha(t.inputString[t.begTok+1])
 }
 
 /*IdentifierOrReserved advances characters until it finds a non alphanumeric character and then checks to see
@@ -411,6 +910,14 @@ func (t *Tokenizer) PeekNext() byte {
 	return 0
 }
 
+/*PeekAt looks at the character the given distance past the next character */
+func (t *Tokenizer) PeekAt(distance int) byte {
+	if t.cursorLoc+distance < len(t.inputString) {
+		return t.inputString[t.cursorLoc+distance]
+	}
+	return 0
+}
+
 /*AtEnd returns true if we have reached the end of the line to be read in, otherwise false */
 func (t *Tokenizer) AtEnd() bool {
 	return t.cursorLoc > len(t.inputString)-1
@@ -418,7 +925,19 @@ func (t *Tokenizer) AtEnd() bool {
 
 /*AddToken adds a token to the token list contained within the Tokenizer object */
 func (t *Tokenizer) AddToken(tokenType TokenType, literal string) {
-	token := Token{tokenType, literal, t.lineNo}
+	token := Token{Type: tokenType, literal: literal, pos: t.Position()}
 	t.begTok = t.cursorLoc
 	t.tokens = append(t.tokens, token)
 }
+
+/*PositionAt returns the location of a span of the line the cursor is currently on */
+func (t *Tokenizer) PositionAt(offset int, length int) Position {
+	column := utf8.RuneCountInString(t.inputString[t.lineStart:offset]) + 1
+	return Position{t.source, t.lineNo, column, offset, length}
+}
+
+/*Position returns the location of the token currently being read, from its first character to the cursor */
+func (t *Tokenizer) Position() Position {
+	column := utf8.RuneCountInString(t.inputString[t.tokStart:t.begTok]) + 1
+	return Position{t.source, t.tokLine, column, t.begTok, t.cursorLoc - t.begTok}
+}
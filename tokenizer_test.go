@@ -0,0 +1,146 @@
+package butter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+/*literalTests are single literals, along with the value each one is read as or the errors it reports */
+var literalTests = []struct {
+	name string
+	src  string
+	want Object
+	err  string
+}{
+	// escape sequences
+	{"simple escapes", `"a\n\t\r\\\"\'\$b"`, String{"a\n\t\r\\\"'$b"}, ""},
+	{"nul escape", `"\0"`, String{"\x00"}, ""},
+	{"hex escape", `"\x41\x7a"`, String{"Az"}, ""},
+	{"unicode escape", `"caf\u00e9 \u00E9"`, String{"café é"}, ""},
+	{"long unicode escape", `"\U0001F600"`, String{"😀"}, ""},
+	{"unknown escape", `"a\qb"`, nil, "PARSE_ERROR [test:1:3]: Invalid escape sequence '\\q'"},
+	{"short hex escape", `"\x4"`, nil, "PARSE_ERROR [test:1:2]: Escape sequence '\\x4' expects 2 hex digits"},
+	{"short unicode escape", `"\u12g4"`, nil, "PARSE_ERROR [test:1:2]: Escape sequence '\\u12' expects 4 hex digits"},
+	{"surrogate escape", `"\uD800"`, nil, "PARSE_ERROR [test:1:2]: Escape sequence '\\uD800' is not a valid code point"},
+	{"escape past the last code point", `"\U00110000"`, nil, "PARSE_ERROR [test:1:2]: Escape sequence '\\U00110000' is not a valid code point"},
+	{"unclosed string", `"abc`, nil, "PARSE_ERROR [test:1:1]: Unclosed string literal, use \"\"\" for strings spanning several lines"},
+	{"line continuation", "\"abc\\\ndef\"", String{"abcdef"}, ""},
+	// raw strings
+	{"raw string", "`a\\nb${c}`", String{"a\\nb${c}"}, ""},
+	{"multi-line raw string", "`a\nb`", String{"a\nb"}, ""},
+	{"unclosed raw string", "`abc", nil, "PARSE_ERROR [test:1:1]: Unclosed raw string literal"},
+	// triple-quoted strings
+	{"triple-quoted string", "\"\"\"a\n\"b\"\n\\tc\"\"\"", String{"a\n\"b\"\n\tc"}, ""},
+	{"triple-quoted line continuation", "\"\"\"a\\\nb\"\"\"", String{"ab"}, ""},
+	{"error after a line continuation", "\"\"\"a\\\nb\"\"\"\n\"\\q\"", nil, "PARSE_ERROR [test:3:2]: Invalid escape sequence '\\q'"},
+	{"unclosed nested comment", "/* a /* b */ 1", nil, "PARSE_ERROR [test:1:1]: Unclosed block comment"},
+	{"unclosed triple-quoted string", "\"\"\"a\nb", nil, "PARSE_ERROR [test:1:1]: Unclosed multi-line string literal"},
+	// interpolation
+	{"unclosed interpolation", `"${x`, nil, "PARSE_ERROR [test:1:1]: Unclosed string literal, use \"\"\" for strings spanning several lines\nPARSE_ERROR [test:1:2]: Unclosed '${' in string"},
+	{"unclosed interpolation with brackets", `"a ${ {1: 2}[1] "`, nil, "PARSE_ERROR [test:1:1]: Unclosed string literal, use \"\"\" for strings spanning several lines\nPARSE_ERROR [test:1:4]: Unclosed '${' in string"},
+	{"empty interpolation", `"${}"`, nil, "PARSE_ERROR [test:1:4]: Expect expression, received->EOF "},
+	{"several expressions in an interpolation", `"${1 2}"`, nil, "PARSE_ERROR [test:1:6]: Expect '}' after interpolated expression"},
+	{"invalid format specifier", `"${1:z}"`, nil, "PARSE_ERROR [test:1:6]: Invalid format specifier 'z'"},
+	{"format specifier with two precisions", `"${1:5.2.1f}"`, nil, "PARSE_ERROR [test:1:6]: Invalid format specifier '5.2.1f'"},
+	// numbers
+	{"decimal", "1234", Integer{1234}, ""},
+	{"leading zeros", "007", Integer{7}, ""},
+	{"hex", "0xFf", Integer{255}, ""},
+	{"octal", "0o17", Integer{15}, ""},
+	{"binary", "0b1010", Integer{10}, ""},
+	{"separators", "1_000_000", Integer{1000000}, ""},
+	{"separators after a prefix", "0xFF_FF", Integer{65535}, ""},
+	{"largest int", "9223372036854775807", Integer{9223372036854775807}, ""},
+	{"lowest int", "-9223372036854775808", Integer{-9223372036854775808}, ""},
+	{"lowest int in hex", "-0x8000000000000000", Integer{-9223372036854775808}, ""},
+	{"int overflow", "9223372036854775808", nil, "PARSE_ERROR [test:1:1]: Integer literal '9223372036854775808' overflows int"},
+	{"negated int overflow", "-9223372036854775809", nil, "PARSE_ERROR [test:1:2]: Integer literal '9223372036854775809' overflows int"},
+	{"hex overflow", "0x1_0000_0000_0000_0000", nil, "PARSE_ERROR [test:1:1]: Integer literal '0x1_0000_0000_0000_0000' overflows int"},
+	{"bad digit", "0b102", nil, "PARSE_ERROR [test:1:1]: Invalid integer literal '0b102'"},
+	{"empty prefix", "0x", nil, "PARSE_ERROR [test:1:1]: Invalid integer literal '0x'"},
+	{"doubled separator", "1__0", nil, "PARSE_ERROR [test:1:1]: Invalid integer literal '1__0'"},
+	{"trailing separator", "10_", nil, "PARSE_ERROR [test:1:1]: Invalid integer literal '10_'"},
+	{"float", "1.5", Float{1.5}, ""},
+	{"exponent", "6.02e23", Float{6.02e23}, ""},
+	{"negative exponent", "25E-2", Float{0.25}, ""},
+	{"float separators", "1_000.000_5", Float{1000.0005}, ""},
+	{"exponent after a bare dot", "1.e5", Float{1e5}, ""},
+	{"signed exponent after a bare dot", "2.E-1", Float{0.2}, ""},
+	{"bare dot", "3.", Float{3}, ""},
+	{"float overflow", "1e400", nil, "PARSE_ERROR [test:1:1]: Float literal '1e400' overflows float"},
+	{"empty exponent", "1e", nil, "PARSE_ERROR [test:1:1]: Invalid float literal '1e'"},
+}
+
+func TestLiterals(t *testing.T) {
+	for _, test := range literalTests {
+		t.Run(test.name, func(t *testing.T) {
+			stmts, err := Parse(NewSource("test", test.src))
+			if test.err != "" {
+				if got := errorString(err); got != test.err {
+					t.Errorf("%s reported %q, want %q", test.src, got, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsing %s: %v", test.src, err)
+			}
+			literal, ok := stmts[0].(ExprStmt).expr.(Literal)
+			if !ok {
+				t.Fatalf("%s parsed as %s, want a literal", test.src, StmtString(stmts[0]))
+			}
+			if literal.obj != test.want {
+				t.Errorf("%s read as %#v, want %#v", test.src, literal.obj, test.want)
+			}
+		})
+	}
+}
+
+/*tokenLines renders each token with the line it starts on, along with the text of comments */
+func tokenLines(tokens []Token) string {
+	var lines []string
+	for _, token := range tokens {
+		if token.Type == COMMENT {
+			lines = append(lines, fmt.Sprintf("COMMENT(%q):%d", token.literal, token.pos.Line))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s:%d", token.Type, token.pos.Line))
+	}
+	return strings.Join(lines, " ")
+}
+
+func TestTokenLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		comments bool
+		want     string
+	}{
+		{"plain lines", "a\nb\n\nc", false, `IDENTIFIER:1 \n:1 IDENTIFIER:2 \n:2 \n:3 IDENTIFIER:4 EOF:4`},
+		{"triple-quoted string", "a := \"\"\"x\ny\nz\"\"\"\nb", false, `IDENTIFIER:1 ASSIGN:1 STRING:1 \n:3 IDENTIFIER:4 EOF:4`},
+		{"raw string", "`x\ny`\nb", false, `STRING:1 \n:2 IDENTIFIER:3 EOF:3`},
+		{"escaped newline", "\"x\\ny\"\nb", false, `STRING:1 \n:1 IDENTIFIER:2 EOF:2`},
+		{"line continuation", "\"x\\\ny\"\nb", false, `STRING:1 \n:2 IDENTIFIER:3 EOF:3`},
+		{"triple-quoted line continuation", "\"\"\"x\\\ny\"\"\"\nb", false, `STRING:1 \n:2 IDENTIFIER:3 EOF:3`},
+		{"interpolation after a multi-line string", "\"\"\"x\n\"\"\" \"${a}\"\nb", false, `STRING:1 INTERPOLATION:2 \n:2 IDENTIFIER:3 EOF:3`},
+		{"line comment", "a // note\nb", false, `IDENTIFIER:1 \n:1 IDENTIFIER:2 EOF:2`},
+		{"block comment", "a /* x\ny */ b\nc", false, `IDENTIFIER:1 IDENTIFIER:2 \n:2 IDENTIFIER:3 EOF:3`},
+		{"nested block comment", "/* a /* b\n*/ c */ d\ne", false, `IDENTIFIER:2 \n:2 IDENTIFIER:3 EOF:3`},
+		{"kept line comment", "a // note\nb", true, `IDENTIFIER:1 COMMENT("// note"):1 \n:1 IDENTIFIER:2 EOF:2`},
+		{"kept block comment", "a /* x\ny */ b\nc", true, `IDENTIFIER:1 COMMENT("/* x\ny */"):1 IDENTIFIER:2 \n:2 IDENTIFIER:3 EOF:3`},
+		{"kept nested block comment", "/* a /* b\n*/ c */ d", true, `COMMENT("/* a /* b\n*/ c */"):1 IDENTIFIER:2 EOF:2`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokenizer := NewTokenizer(NewSource("test", test.src))
+			tokenizer.KeepComments(test.comments)
+			tokens := tokenizer.Tokenize()
+			if errs := tokenizer.Errors(); len(errs) > 0 {
+				t.Fatalf("tokenizing %q: %v", test.src, ParseErrors(errs))
+			}
+			if got := tokenLines(tokens); got != test.want {
+				t.Errorf("%q tokenized as\n%s\nwant\n%s", test.src, got, test.want)
+			}
+		})
+	}
+}
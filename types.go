@@ -0,0 +1,113 @@
+package butter
+
+import "strings"
+
+/*TypeSpec is a type as it is written in a declaration, such as int, list<string> or the name of a struct.
+  Container types hold the types of their elements in params */
+type TypeSpec struct {
+	token  Token
+	params []TypeSpec
+}
+
+/*String returns the type as it is written in source */
+func (t TypeSpec) String() string {
+	name := TypeName(t.token)
+	if len(t.params) == 0 {
+		return name
+	}
+	params := make([]string, len(t.params))
+	for idx, param := range t.params {
+		params[idx] = param.String()
+	}
+	return name + "<" + strings.Join(params, ", ") + ">"
+}
+
+/*Inferred returns true if the type was left out with var or let, to be inferred from the initializer */
+func (t TypeSpec) Inferred() bool {
+	return t.token.Type == VAR || t.token.Type == LET
+}
+
+/*Equals returns true if both specs describe the same type */
+func (t TypeSpec) Equals(other TypeSpec) bool {
+	if t.token.Type != other.token.Type || len(t.params) != len(other.params) {
+		return false
+	}
+	//struct types are named by an identifier
+	if t.token.Type == IDENTIFIER && t.token.literal != other.token.literal {
+		return false
+	}
+	for idx := range t.params {
+		if !t.params[idx].Equals(other.params[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+/*typeKeywords are the tokens which begin a type */
+var typeKeywords = []TokenType{INTTYPE, FLOATTYPE, STRINGTYPE, BOOLTYPE, LISTTYPE, MAPTYPE}
+
+/*keyTypes are the types which can be used as the keys of a map */
+var keyTypes = []TokenType{INTTYPE, FLOATTYPE, STRINGTYPE, BOOLTYPE}
+
+/*InferType returns the type of a value, or false if the value has no type which can be declared. Lists and
+  maps whose types have not been pinned take the type shared by all of their entries, so empty ones have none */
+func InferType(val Object) (TypeSpec, bool) {
+	switch v := val.(type) {
+	case Integer:
+		return keywordType(INTTYPE, "int"), true
+	case Float:
+		return keywordType(FLOATTYPE, "float"), true
+	case Boolean:
+		return keywordType(BOOLTYPE, "bool"), true
+	case String:
+		return keywordType(STRINGTYPE, "string"), true
+	case *Instance:
+		return keywordType(IDENTIFIER, v.Type()), true
+	case *List:
+		spec := keywordType(LISTTYPE, "list")
+		if v.elemType != nil {
+			spec.params = []TypeSpec{*v.elemType}
+			return spec, true
+		}
+		elemType, ok := commonType(v.Elements)
+		spec.params = []TypeSpec{elemType}
+		return spec, ok
+	case *Map:
+		spec := keywordType(MAPTYPE, "map")
+		if v.keyType != nil {
+			spec.params = []TypeSpec{*v.keyType, *v.valueType}
+			return spec, true
+		}
+		keyType, keysOK := commonType(v.keys)
+		values := make([]Object, 0, len(v.values))
+		for _, value := range v.values {
+			values = append(values, value)
+		}
+		valueType, valuesOK := commonType(values)
+		spec.params = []TypeSpec{keyType, valueType}
+		return spec, keysOK && valuesOK
+	}
+	return TypeSpec{}, false
+}
+
+/*commonType returns the type shared by every value, or false if there are none or their types differ */
+func commonType(values []Object) (TypeSpec, bool) {
+	if len(values) == 0 {
+		return TypeSpec{}, false
+	}
+	first, ok := InferType(values[0])
+	if !ok {
+		return TypeSpec{}, false
+	}
+	for _, value := range values[1:] {
+		if spec, ok := InferType(value); !ok || !spec.Equals(first) {
+			return TypeSpec{}, false
+		}
+	}
+	return first, true
+}
+
+func keywordType(tokenType TokenType, name string) TypeSpec {
+	return TypeSpec{token: Token{Type: tokenType, literal: name}}
+}